@@ -1,18 +1,26 @@
 package radiko
 
 import (
-	"net/http"
+	"context"
 
 	"golang.org/x/net/html"
 )
 
-const (
-	areaURL = "http://radiko.jp/area"
-)
-
 // AreaID returns areaID.
+//
+// Deprecated: Use Client.GetAreaID, which supports context and custom HTTP clients.
 func AreaID() (string, error) {
-	resp, err := http.Get(areaURL)
+	return defaultClient().GetAreaID(context.Background())
+}
+
+// GetAreaID returns the areaID detected by radiko.jp from the client's IP address.
+func (c *Client) GetAreaID(ctx context.Context) (string, error) {
+	req, err := c.newRequest(ctx, "GET", "area", &Params{})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.Do(req)
 	if err != nil {
 		return "", err
 	}
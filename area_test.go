@@ -1,6 +1,10 @@
 package radiko
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -32,3 +36,23 @@ func TestProcessSpanNode(t *testing.T) {
 			"Failed to process span node.\nAreaID: %s", areaID)
 	}
 }
+
+func TestClient_GetAreaID(t *testing.T) {
+	const expected = "JP27"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/area" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `document.write('<span class="%s">OSAKA JAPAN</span>');`, expected)
+	}))
+	defer ts.Close()
+
+	areaID, err := newTestClient(t, ts).GetAreaID(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if areaID != expected {
+		t.Errorf("expected %s, but %s", expected, areaID)
+	}
+}
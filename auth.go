@@ -2,49 +2,78 @@ package radiko
 
 import (
 	"context"
-	"encoding/base64"
-	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"strconv"
 	"strings"
 )
 
-// AuthorizeToken returns an enables auth_token and error,
+// AuthResult is the result of AuthorizeToken.
+type AuthResult struct {
+	// AuthToken is the enabled auth_token.
+	AuthToken string
+	// Area is the area the token was issued for.
+	Area AuthArea
+}
+
+// AuthorizeToken returns an enabled auth_token and the area it was issued for,
 // and sets auth_token in Client.
-// Is is a alias function that wraps Auth1 and Auth2.
-func (c *Client) AuthorizeToken(ctx context.Context) (string, error) {
-	authToken, length, offset, err := c.Auth1(ctx)
+// The token is authorized by the client's Authenticator,
+// which wraps Auth1 and Auth2 of radiko's HTML5 player by default.
+//
+// If SetSyncAreaID(true) has been called, the client's area is also set to the area of the token,
+// which may differ from the area detected by GetAreaID (e.g. behind a VPN).
+func (c *Client) AuthorizeToken(ctx context.Context) (AuthResult, error) {
+	authToken, slc, err := c.Authenticator().Authenticate(ctx, c)
 	if err != nil {
-		return "", err
+		return AuthResult{}, err
 	}
-
-	b := radikoAuthkeyValue[offset : offset+length]
-	partialKey := base64.StdEncoding.EncodeToString([]byte(b))
-
-	slc, err := c.Auth2(ctx, authToken, partialKey)
+	area, err := ParseAuth2Response(slc)
 	if err != nil {
-		return "", err
+		return AuthResult{}, err
 	}
-	if err := verifyAuth2Response(slc); err != nil {
-		return "", err
+
+	c.mu.Lock()
+	c.authTokenHeader = authToken
+	if c.syncAreaID {
+		c.areaID = area.ID
 	}
+	c.mu.Unlock()
+	return AuthResult{AuthToken: authToken, Area: area}, nil
+}
+
+// SyncAreaID reports whether AuthorizeToken sets the client's area to the area of the token.
+func (c *Client) SyncAreaID() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.syncAreaID
+}
 
-	c.setAuthTokenHeader(authToken)
-	return authToken, nil
+// SetSyncAreaID sets whether AuthorizeToken sets the client's area to the area of the token,
+// so that program and playlist requests use the area the token was actually issued for.
+func (c *Client) SetSyncAreaID(sync bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.syncAreaID = sync
 }
 
 // Auth1 returns authToken, keyLength, keyOffset and error.
+// The request identifies the app with the client's DeviceProfile.
 func (c *Client) Auth1(ctx context.Context) (string, int64, int64, error) {
+	device, err := c.device()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	return c.auth1(ctx, device.header())
+}
+
+// auth1 requests auth1 with the headers which identify the app.
+func (c *Client) auth1(ctx context.Context, header map[string]string) (string, int64, int64, error) {
 	apiEndpoint := apiPath(apiV2, "auth1")
 
 	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{
-		header: map[string]string{
-			radikoAppHeader:        radikoApp,
-			radikoAppVersionHeader: radikoAppVersion,
-			radikoUserHeader:       radikoUser,
-			radikoDeviceHeader:     radikoDevice,
-		},
+		header: header,
 	})
 	if err != nil {
 		return "", 0, 0, err
@@ -55,34 +84,58 @@ func (c *Client) Auth1(ctx context.Context) (string, int64, int64, error) {
 		return "", 0, 0, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", 0, 0, &AuthStatusError{Op: "auth1", StatusCode: resp.StatusCode}
+	}
 
 	authToken := resp.Header.Get(radikoAuthTokenHeader)
-	keyLength := resp.Header.Get(radikoKeyLentghHeader)
-	keyOffset := resp.Header.Get(radikoKeyOffsetHeader)
-
-	length, err := strconv.ParseInt(keyLength, 10, 64)
+	if authToken == "" {
+		return "", 0, 0, &AuthHeaderError{Op: "auth1", Header: radikoAuthTokenHeader}
+	}
+	length, err := intHeader(resp.Header, "auth1", radikoKeyLentghHeader)
 	if err != nil {
 		return "", 0, 0, err
 	}
-	offset, err := strconv.ParseInt(keyOffset, 10, 64)
+	offset, err := intHeader(resp.Header, "auth1", radikoKeyOffsetHeader)
 	if err != nil {
 		return "", 0, 0, err
 	}
 
-	return authToken, length, offset, err
+	return authToken, length, offset, nil
+}
+
+// intHeader returns the integer value of the response header.
+func intHeader(h http.Header, op, key string) (int64, error) {
+	v := h.Get(key)
+	if v == "" {
+		return 0, &AuthHeaderError{Op: op, Header: key}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil {
+		return 0, &AuthHeaderError{Op: op, Header: key, Value: v}
+	}
+	return n, nil
 }
 
 // Auth2 enables the given authToken.
+// The request identifies the app with the client's DeviceProfile.
 func (c *Client) Auth2(ctx context.Context, authToken, partialKey string) ([]string, error) {
+	device, err := c.device()
+	if err != nil {
+		return nil, err
+	}
+	header := device.header()
+	header[radikoAuthTokenHeader] = authToken
+	header[radikoPartialKeyHeader] = partialKey
+	return c.auth2(ctx, header)
+}
+
+// auth2 requests auth2 with the headers which identify the app and the partial key.
+func (c *Client) auth2(ctx context.Context, header map[string]string) ([]string, error) {
 	apiEndpoint := apiPath(apiV2, "auth2")
 
 	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{
-		header: map[string]string{
-			radikoUserHeader:       radikoUser,
-			radikoDeviceHeader:     radikoDevice,
-			radikoAuthTokenHeader:  authToken,
-			radikoPartialKeyHeader: partialKey,
-		},
+		header: header,
 	})
 	if err != nil {
 		return nil, err
@@ -93,6 +146,9 @@ func (c *Client) Auth2(ctx context.Context, authToken, partialKey string) ([]str
 		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &AuthStatusError{Op: "auth2", StatusCode: resp.StatusCode}
+	}
 
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -103,14 +159,34 @@ func (c *Client) Auth2(ctx context.Context, authToken, partialKey string) ([]str
 	return s, nil
 }
 
-func verifyAuth2Response(slc []string) error {
-	if len(slc) == 0 {
-		return errors.New("missing token")
+// AuthArea is the area authorized by auth2.
+type AuthArea struct {
+	// ID is the area ID, e.g. "JP13".
+	ID string
+	// Name is the prefecture name, e.g. "東京都".
+	Name string
+	// NameEn is the English name, e.g. "tokyo Japan".
+	NameEn string
+}
+
+// ParseAuth2Response returns the area of the response of auth2,
+// e.g. "JP13,東京都,tokyo Japan".
+// It returns ErrOutsideJapan if the response is not an area of Japan.
+func ParseAuth2Response(slc []string) (AuthArea, error) {
+	if len(slc) == 0 || strings.TrimSpace(slc[0]) == "" {
+		return AuthArea{}, fmt.Errorf("auth2: %w: empty response", ErrOutsideJapan)
 	}
-	s := strings.TrimSpace(slc[0])
-	if !strings.HasPrefix(s, "JP") {
-		return fmt.Errorf("invalid token: %s", s)
+	id := strings.TrimSpace(slc[0])
+	if !strings.HasPrefix(id, "JP") {
+		return AuthArea{}, fmt.Errorf("auth2: %w: %q", ErrOutsideJapan, id)
 	}
 
-	return nil
+	area := AuthArea{ID: id}
+	if len(slc) > 1 {
+		area.Name = strings.TrimSpace(slc[1])
+	}
+	if len(slc) > 2 {
+		area.NameEn = strings.TrimSpace(strings.Join(slc[2:], ","))
+	}
+	return area, nil
 }
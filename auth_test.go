@@ -3,6 +3,10 @@ package radiko
 import (
 	"context"
 	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -17,11 +21,11 @@ func TestAuthorizeToken(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	authToken, err := c.AuthorizeToken(ctx)
+	result, err := c.AuthorizeToken(ctx)
 	if err != nil {
 		t.Error(err)
 	}
-	if len(authToken) == 0 {
+	if len(result.AuthToken) == 0 {
 		t.Error("AuthToken is empty.")
 	}
 }
@@ -85,7 +89,7 @@ func TestVerifyAuth2FmsResponse(t *testing.T) {
 		},
 	}
 	for _, c := range cases {
-		err := verifyAuth2Response(c.slc)
+		_, err := ParseAuth2Response(c.slc)
 		if c.expectedErr {
 			if err == nil {
 				t.Error("Should detect an error.")
@@ -97,3 +101,164 @@ func TestVerifyAuth2FmsResponse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseAuth2Response(t *testing.T) {
+	area, err := ParseAuth2Response([]string{"JP13", "東京都", "tokyo Japan\r\n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AuthArea{ID: "JP13", Name: "東京都", NameEn: "tokyo Japan"}
+	if area != expected {
+		t.Errorf("expected %+v, but %+v", expected, area)
+	}
+
+	for _, slc := range [][]string{nil, {""}, {"OUT"}} {
+		if _, err := ParseAuth2Response(slc); !errors.Is(err, ErrOutsideJapan) {
+			t.Errorf("Should detect ErrOutsideJapan: %q, %v", slc, err)
+		}
+	}
+}
+
+func TestAuthErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		auth1   func(w http.ResponseWriter)
+		auth2   func(w http.ResponseWriter)
+		checkFn func(err error) bool
+	}{
+		{
+			name:  "auth1 status",
+			auth1: func(w http.ResponseWriter) { w.WriteHeader(http.StatusForbidden) },
+			checkFn: func(err error) bool {
+				var e *AuthStatusError
+				return errors.As(err, &e) && e.Op == "auth1" && e.StatusCode == http.StatusForbidden
+			},
+		},
+		{
+			name: "missing token",
+			auth1: func(w http.ResponseWriter) {
+				w.Header().Set(radikoKeyLentghHeader, "16")
+				w.Header().Set(radikoKeyOffsetHeader, "0")
+			},
+			checkFn: func(err error) bool {
+				var e *AuthHeaderError
+				return errors.As(err, &e) && e.Header == radikoAuthTokenHeader
+			},
+		},
+		{
+			name: "missing key length",
+			auth1: func(w http.ResponseWriter) {
+				w.Header().Set(radikoAuthTokenHeader, "token")
+				w.Header().Set(radikoKeyOffsetHeader, "0")
+			},
+			checkFn: func(err error) bool {
+				var e *AuthHeaderError
+				return errors.As(err, &e) && e.Header == radikoKeyLentghHeader && e.Value == ""
+			},
+		},
+		{
+			name: "invalid key offset",
+			auth1: func(w http.ResponseWriter) {
+				w.Header().Set(radikoAuthTokenHeader, "token")
+				w.Header().Set(radikoKeyLentghHeader, "16")
+				w.Header().Set(radikoKeyOffsetHeader, "x")
+			},
+			checkFn: func(err error) bool {
+				var e *AuthHeaderError
+				return errors.As(err, &e) && e.Header == radikoKeyOffsetHeader && e.Value == "x"
+			},
+		},
+		{
+			name: "key range",
+			auth1: func(w http.ResponseWriter) {
+				w.Header().Set(radikoAuthTokenHeader, "token")
+				w.Header().Set(radikoKeyLentghHeader, "16")
+				w.Header().Set(radikoKeyOffsetHeader, "100")
+			},
+			checkFn: func(err error) bool {
+				var e *KeyRangeError
+				return errors.As(err, &e) && e.Offset == 100 && e.KeyLength == len(radikoAuthkeyValue)
+			},
+		},
+		{
+			name:  "auth2 status",
+			auth2: func(w http.ResponseWriter) { w.WriteHeader(http.StatusUnauthorized) },
+			checkFn: func(err error) bool {
+				var e *AuthStatusError
+				return errors.As(err, &e) && e.Op == "auth2" && e.StatusCode == http.StatusUnauthorized
+			},
+		},
+		{
+			name:  "outside of Japan",
+			auth2: func(w http.ResponseWriter) { fmt.Fprint(w, "OUT") },
+			checkFn: func(err error) bool {
+				return errors.Is(err, ErrOutsideJapan)
+			},
+		},
+	}
+
+	for _, c := range cases {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.URL.Path == "/v2/api/auth1" && c.auth1 != nil:
+				c.auth1(w)
+			case r.URL.Path == "/v2/api/auth1":
+				w.Header().Set(radikoAuthTokenHeader, "token")
+				w.Header().Set(radikoKeyLentghHeader, "16")
+				w.Header().Set(radikoKeyOffsetHeader, "0")
+			case r.URL.Path == "/v2/api/auth2" && c.auth2 != nil:
+				c.auth2(w)
+			default:
+				fmt.Fprint(w, "JP13,東京都,tokyo Japan")
+			}
+		}))
+
+		client := newTestClient(t, ts)
+		_, err := client.AuthorizeToken(context.Background())
+		if !c.checkFn(err) {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		}
+		if client.AuthToken() != "" {
+			t.Errorf("%s: Should not set the token: %s", c.name, client.AuthToken())
+		}
+		ts.Close()
+	}
+}
+
+func TestClient_SyncAreaID(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/api/auth1":
+			w.Header().Set(radikoAuthTokenHeader, "token")
+			w.Header().Set(radikoKeyLentghHeader, "16")
+			w.Header().Set(radikoKeyOffsetHeader, "0")
+		default:
+			fmt.Fprint(w, "JP27,大阪府,osaka Japan\r\n")
+		}
+	}))
+	defer ts.Close()
+
+	for _, sync := range []bool{false, true} {
+		client := newTestClient(t, ts)
+		client.SetSyncAreaID(sync)
+		result, err := client.AuthorizeToken(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to authorize: %s", err)
+		}
+		expected := AuthResult{
+			AuthToken: "token",
+			Area:      AuthArea{ID: "JP27", Name: "大阪府", NameEn: "osaka Japan"},
+		}
+		if result != expected {
+			t.Errorf("expected %+v, but %+v", expected, result)
+		}
+
+		areaID := areaIDTokyo
+		if sync {
+			areaID = "JP27"
+		}
+		if client.AreaID() != areaID {
+			t.Errorf("sync=%v: expected %s, but %s", sync, areaID, client.AreaID())
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package radiko
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Authenticator performs auth1 and auth2,
+// and returns the enabled auth_token and the response of auth2.
+type Authenticator interface {
+	Authenticate(ctx context.Context, c *Client) (string, []string, error)
+}
+
+// HTML5Authenticator is the Authenticator of radiko's HTML5 player, which is used by default.
+// The partial key is cut out of the key given by the client's AuthKeyProvider.
+type HTML5Authenticator struct{}
+
+// Authenticate implements the Authenticator interface.
+func (HTML5Authenticator) Authenticate(ctx context.Context, c *Client) (string, []string, error) {
+	key, err := c.AuthKeyProvider().AuthKey(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	authToken, length, offset, err := c.Auth1(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pk, err := partialKey(key, offset, length)
+	if err != nil {
+		return "", nil, err
+	}
+
+	slc, err := c.Auth2(ctx, authToken, pk)
+	if err != nil {
+		return "", nil, err
+	}
+	return authToken, slc, nil
+}
+
+// MobileAuthenticator is the Authenticator of radiko's smartphone app,
+// which reports the location of the device in auth2.
+//
+// It is meant for premium members who need a token of a specific area.
+// The key of the app must be given by Key, e.g. a FileAuthKey.
+//
+// The app is identified by the client's DeviceProfile, which is set to AndroidDevice
+// if the client has no profile, so that the playlists are requested as the same device.
+type MobileAuthenticator struct {
+	// Key provides the key of the app.
+	Key AuthKeyProvider
+	// AreaID is the area to authorize, which defaults to the client's area.
+	AreaID string
+	// Location overrides the coordinates of the area.
+	Location *Location
+	// Connection is sent as X-Radiko-Connection, which defaults to "wifi".
+	Connection string
+}
+
+// Authenticate implements the Authenticator interface.
+// It fails if radiko authorizes another area than the requested one.
+func (a *MobileAuthenticator) Authenticate(ctx context.Context, c *Client) (string, []string, error) {
+	if a.Key == nil {
+		return "", nil, errors.New("MobileAuthenticator needs the key of the app")
+	}
+
+	areaID := a.AreaID
+	if areaID == "" {
+		areaID = c.AreaID()
+	}
+	var loc Location
+	if a.Location != nil {
+		loc = *a.Location
+	} else {
+		var ok bool
+		if loc, ok = AreaLocation(areaID); !ok {
+			return "", nil, fmt.Errorf("unknown area: %q", areaID)
+		}
+	}
+
+	c.setDefaultDeviceProfile(AndroidDevice())
+	device, err := c.device()
+	if err != nil {
+		return "", nil, err
+	}
+
+	key, err := a.Key.AuthKey(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	authToken, length, offset, err := c.auth1(ctx, device.header())
+	if err != nil {
+		return "", nil, err
+	}
+
+	pk, err := partialKey(key, offset, length)
+	if err != nil {
+		return "", nil, err
+	}
+
+	header := device.header()
+	header[radikoAuthTokenHeader] = authToken
+	header[radikoPartialKeyHeader] = pk
+	header[radikoLocationHeader] = loc.header()
+	header[radikoConnectionHeader] = a.Connection
+	if a.Connection == "" {
+		header[radikoConnectionHeader] = "wifi"
+	}
+	slc, err := c.auth2(ctx, header)
+	if err != nil {
+		return "", nil, err
+	}
+	area, err := ParseAuth2Response(slc)
+	if err != nil {
+		return "", nil, err
+	}
+	if area.ID != areaID {
+		return "", nil, fmt.Errorf("authorized in %s instead of %s", area.ID, areaID)
+	}
+	return authToken, slc, nil
+}
+
+// Authenticator returns the authenticator used by AuthorizeToken.
+func (c *Client) Authenticator() Authenticator {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.authenticator == nil {
+		return HTML5Authenticator{}
+	}
+	return c.authenticator
+}
+
+// SetAuthenticator sets the authenticator used by AuthorizeToken.
+// nil resets it to HTML5Authenticator.
+func (c *Client) SetAuthenticator(a Authenticator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.authenticator = a
+}
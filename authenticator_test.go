@@ -0,0 +1,171 @@
+package radiko
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newMobileAuthTestServer returns a server which authorizes the area of the location.
+// The users of the requests are sent to users if not full.
+func newMobileAuthTestServer(t *testing.T, key []byte, areaID string, users chan<- string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case users <- r.Header.Get(radikoUserHeader):
+		default:
+		}
+		if r.Header.Get(radikoAppHeader) != "aSmartPhone7a" {
+			t.Errorf("unexpected %s: %s", radikoAppHeader, r.Header.Get(radikoAppHeader))
+		}
+		if ua := r.Header.Get("User-Agent"); ua != AndroidDevice().UserAgent {
+			t.Errorf("unexpected User-Agent: %s", ua)
+		}
+		if len(r.Header.Get(radikoUserHeader)) != 32 {
+			t.Errorf("unexpected %s: %s", radikoUserHeader, r.Header.Get(radikoUserHeader))
+		}
+
+		switch r.URL.Path {
+		case "/v2/api/auth1":
+			w.Header().Set(radikoAuthTokenHeader, "mobile-token")
+			w.Header().Set(radikoKeyLentghHeader, "4")
+			w.Header().Set(radikoKeyOffsetHeader, "2")
+		case "/v2/api/auth2":
+			if pk := base64.StdEncoding.EncodeToString(key[2:6]); r.Header.Get(radikoPartialKeyHeader) != pk {
+				t.Errorf("expected %s, but %s", pk, r.Header.Get(radikoPartialKeyHeader))
+			}
+			if r.Header.Get(radikoConnectionHeader) != "wifi" {
+				t.Errorf("unexpected %s: %s", radikoConnectionHeader, r.Header.Get(radikoConnectionHeader))
+			}
+			loc, _ := AreaLocation(areaID)
+			if r.Header.Get(radikoLocationHeader) != loc.header() {
+				fmt.Fprint(w, "JP13,東京都,tokyo Japan")
+				return
+			}
+			fmt.Fprintf(w, "%s,somewhere,Japan", areaID)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestMobileAuthenticator(t *testing.T) {
+	key := []byte("0123456789")
+	ts := newMobileAuthTestServer(t, key, "JP27", nil)
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	client.SetAuthenticator(&MobileAuthenticator{
+		Key:    StaticAuthKey(key),
+		AreaID: "JP27",
+	})
+
+	result, err := client.AuthorizeToken(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to authorize: %s", err)
+	}
+	if result.AuthToken != "mobile-token" || client.AuthToken() != result.AuthToken {
+		t.Errorf("unexpected token: %s, %s", result.AuthToken, client.AuthToken())
+	}
+	if result.Area.ID != "JP27" {
+		t.Errorf("expected JP27, but %s", result.Area.ID)
+	}
+}
+
+func TestMobileAuthenticator_AreaMismatch(t *testing.T) {
+	key := []byte("0123456789")
+	ts := newMobileAuthTestServer(t, key, "JP27", nil)
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	osaka, _ := AreaLocation("JP27")
+	cases := []*MobileAuthenticator{
+		// The location of Tokyo is authorized in JP13.
+		{Key: StaticAuthKey(key), AreaID: "JP27", Location: &Location{35.689488, 139.691706}},
+		// The client's area differs from the location.
+		{Key: StaticAuthKey(key), Location: &osaka},
+		{Key: StaticAuthKey(key), AreaID: "JP99"},
+		{AreaID: "JP27"},
+	}
+	for i, a := range cases {
+		client.SetAuthenticator(a)
+		if _, err := client.AuthorizeToken(context.Background()); err == nil {
+			t.Errorf("Should detect an error: case %d", i)
+		}
+	}
+	if client.AuthToken() != "" {
+		t.Errorf("Should not set the token: %s", client.AuthToken())
+	}
+}
+
+func TestMobileAuthenticator_Device(t *testing.T) {
+	key := []byte("0123456789")
+	users := make(chan string, 2)
+	ts := newMobileAuthTestServer(t, key, "JP27", users)
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	client.SetAuthenticator(&MobileAuthenticator{
+		Key:    StaticAuthKey(key),
+		AreaID: "JP27",
+	})
+	if _, err := client.AuthorizeToken(context.Background()); err != nil {
+		t.Fatalf("Failed to authorize: %s", err)
+	}
+
+	// The playlists are requested as the device of auth.
+	d, err := client.device()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.App != "aSmartPhone7a" {
+		t.Errorf("Should set the profile of the app: %+v", d)
+	}
+	for i := 0; i < 2; i++ {
+		if user := <-users; user != d.User {
+			t.Errorf("expected %s, but %s", d.User, user)
+		}
+	}
+
+	// The profile of the client is used.
+	d = AndroidDevice()
+	d.User = "0123456789abcdef0123456789abcdef"
+	client.SetDeviceProfile(d)
+	if _, err := client.AuthorizeToken(context.Background()); err != nil {
+		t.Fatalf("Failed to authorize: %s", err)
+	}
+	for i := 0; i < 2; i++ {
+		if user := <-users; user != d.User {
+			t.Errorf("expected %s, but %s", d.User, user)
+		}
+	}
+}
+
+func TestClient_Authenticator(t *testing.T) {
+	client := &Client{}
+	if _, ok := client.Authenticator().(HTML5Authenticator); !ok {
+		t.Errorf("unexpected default: %T", client.Authenticator())
+	}
+	client.SetAuthenticator(&MobileAuthenticator{})
+	if _, ok := client.Authenticator().(*MobileAuthenticator); !ok {
+		t.Errorf("unexpected authenticator: %T", client.Authenticator())
+	}
+}
+
+func TestAreaLocation(t *testing.T) {
+	for i := 1; i <= 47; i++ {
+		loc, ok := AreaLocation(fmt.Sprintf("JP%d", i))
+		if !ok || loc.Latitude < 20 || loc.Latitude > 46 || loc.Longitude < 122 || loc.Longitude > 154 {
+			t.Errorf("unexpected location of JP%d: %+v", i, loc)
+		}
+	}
+	if _, ok := AreaLocation("JP0"); ok {
+		t.Error("Should not find JP0.")
+	}
+	loc, _ := AreaLocation("JP13")
+	if expected := "35.689488,139.691706,gps"; loc.header() != expected {
+		t.Errorf("expected %s, but %s", expected, loc.header())
+	}
+}
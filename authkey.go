@@ -0,0 +1,94 @@
+package radiko
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"sync"
+)
+
+// AuthKeyProvider provides the key from which the partial key of auth2 is cut out.
+type AuthKeyProvider interface {
+	AuthKey(ctx context.Context) ([]byte, error)
+}
+
+// StaticAuthKey is an AuthKeyProvider which provides the key as is.
+type StaticAuthKey []byte
+
+// AuthKey implements the AuthKeyProvider interface.
+func (k StaticAuthKey) AuthKey(ctx context.Context) ([]byte, error) {
+	return []byte(k), nil
+}
+
+// HTML5AuthKey returns the provider of the key of radiko's HTML5 player,
+// which is used by default.
+func HTML5AuthKey() AuthKeyProvider {
+	return StaticAuthKey(radikoAuthkeyValue)
+}
+
+// FileAuthKey is an AuthKeyProvider which loads the key from a file,
+// e.g. the binary key of the smartphone app.
+type FileAuthKey struct {
+	Path string
+}
+
+// AuthKey implements the AuthKeyProvider interface.
+func (k *FileAuthKey) AuthKey(ctx context.Context) ([]byte, error) {
+	return ioutil.ReadFile(k.Path)
+}
+
+// SWFAuthKey is an AuthKeyProvider which extracts the key from radiko's legacy flash player.
+// The player is downloaded once and the key is cached.
+type SWFAuthKey struct {
+	// Client downloads the player. nil means a client with the default settings.
+	Client *Client
+
+	mu  sync.Mutex
+	key []byte
+}
+
+// AuthKey implements the AuthKeyProvider interface.
+func (k *SWFAuthKey) AuthKey(ctx context.Context) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.key != nil {
+		return k.key, nil
+	}
+
+	c := k.Client
+	if c == nil {
+		c = defaultClient()
+	}
+	key, err := c.downloadBinary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	k.key = key
+	return key, nil
+}
+
+// AuthKeyProvider returns the provider of the auth key.
+func (c *Client) AuthKeyProvider() AuthKeyProvider {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.authKeyProvider == nil {
+		return HTML5AuthKey()
+	}
+	return c.authKeyProvider
+}
+
+// SetAuthKeyProvider sets the provider of the auth key used by AuthorizeToken.
+// nil resets it to HTML5AuthKey.
+func (c *Client) SetAuthKeyProvider(p AuthKeyProvider) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.authKeyProvider = p
+}
+
+// partialKey returns the base64-encoded part of the key given by auth1.
+func partialKey(key []byte, offset, length int64) (string, error) {
+	if offset < 0 || length <= 0 || offset+length > int64(len(key)) {
+		return "", &KeyRangeError{Offset: offset, Length: length, KeyLength: len(key)}
+	}
+	return base64.StdEncoding.EncodeToString(key[offset : offset+length]), nil
+}
@@ -0,0 +1,132 @@
+package radiko
+
+import (
+	"context"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+func TestPartialKey(t *testing.T) {
+	key := []byte("0123456789")
+	cases := []struct {
+		offset, length int64
+		expected       string
+		expectedErr    bool
+	}{
+		{0, 4, "0123", false},
+		{6, 4, "6789", false},
+		{7, 4, "", true},
+		{-1, 4, "", true},
+		{0, 0, "", true},
+	}
+	for _, c := range cases {
+		pk, err := partialKey(key, c.offset, c.length)
+		if c.expectedErr {
+			if err == nil {
+				t.Errorf("Should detect an error: offset=%d, length=%d", c.offset, c.length)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := base64.StdEncoding.EncodeToString([]byte(c.expected)); pk != expected {
+			t.Errorf("expected %s, but %s", expected, pk)
+		}
+	}
+}
+
+func TestFileAuthKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.bin")
+	if err := ioutil.WriteFile(path, []byte{0, 1, 2, 255}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := (&FileAuthKey{Path: path}).AuthKey(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to load the key: %s", err)
+	}
+	if string(key) != "\x00\x01\x02\xff" {
+		t.Errorf("unexpected key: %v", key)
+	}
+
+	if _, err := (&FileAuthKey{Path: path + ".missing"}).AuthKey(context.Background()); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestClient_AuthKeyProvider(t *testing.T) {
+	key := []byte("abcdefghijklmnopqrstuvwxyz")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/api/auth1":
+			w.Header().Set(radikoAuthTokenHeader, "token")
+			w.Header().Set(radikoKeyLentghHeader, "4")
+			w.Header().Set(radikoKeyOffsetHeader, "20")
+		case "/v2/api/auth2":
+			if r.Header.Get(radikoPartialKeyHeader) != base64.StdEncoding.EncodeToString(key[20:24]) {
+				http.Error(w, "invalid partial key", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("JP13,東京都,tokyo Japan"))
+		}
+	}))
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	if _, ok := client.AuthKeyProvider().(StaticAuthKey); !ok {
+		t.Errorf("HTML5AuthKey should be used by default: %T", client.AuthKeyProvider())
+	}
+
+	client.SetAuthKeyProvider(StaticAuthKey(key))
+	if _, err := client.AuthorizeToken(context.Background()); err != nil {
+		t.Errorf("Failed to authorize: %s", err)
+	}
+
+	client.SetAuthKeyProvider(StaticAuthKey(key[:10]))
+	if _, err := client.AuthorizeToken(context.Background()); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestSWFAuthKey_NilClient(t *testing.T) {
+	key := []byte("abcdefghijklmnopqrstuvwxyz")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+playerPath {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(testSWF(t, targetID, key))
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.URL.Scheme, req.URL.Host = u.Scheme, u.Host
+		return http.DefaultTransport.RoundTrip(req)
+	})})
+	defer teardownHTTPClient()
+
+	b, err := (&SWFAuthKey{}).AuthKey(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to extract the key: %s", err)
+	}
+	if string(b) != string(key) {
+		t.Errorf("expected %s, but %s", key, b)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"path"
 	"runtime"
+	"sync"
 	"time"
 )
 
@@ -30,13 +31,10 @@ const (
 	radikoKeyLentghHeader  = "X-Radiko-KeyLength"
 	radikoKeyOffsetHeader  = "X-Radiko-KeyOffset"
 	radikoPartialKeyHeader = "X-Radiko-Partialkey"
+	radikoLocationHeader   = "X-Radiko-Location"
+	radikoConnectionHeader = "X-Radiko-Connection"
 
 	radikoAuthkeyValue = "bcd151073c03b352e1ef2fd66c32209da9ca0afa"
-
-	radikoApp        = "pc_html5"
-	radikoAppVersion = "0.0.1"
-	radikoUser       = "test-stream"
-	radikoDevice     = "pc"
 )
 
 var (
@@ -48,9 +46,22 @@ var (
 type Client struct {
 	URL *url.URL
 
-	httpClient      *http.Client
+	httpClient *http.Client
+
+	// mu guards the fields below, which are updated by AuthorizeToken
+	// and guide responses while the client is used concurrently.
+	mu              sync.RWMutex
 	authTokenHeader string
 	areaID          string
+	areafreePolicy  AreafreePolicy
+	clockOffset     time.Duration
+	guideCache      *GuideCache
+	authKeyProvider AuthKeyProvider
+	authenticator   Authenticator
+	deviceProfile   *DeviceProfile
+	userID          string
+	syncAreaID      bool
+	timefree30      bool
 }
 
 // New returns a new Client struct.
@@ -70,17 +81,30 @@ func New(authToken string) (*Client, error) {
 		return nil, err
 	}
 
-	areaID, err := AreaID()
+	c := &Client{
+		URL:             parsedURL,
+		httpClient:      httpClient,
+		authTokenHeader: authToken,
+	}
+	areaID, err := c.GetAreaID(context.Background())
 	if err != nil {
 		return nil, err
 	}
+	c.areaID = areaID
+	return c, nil
+}
 
+// defaultClient returns a Client used by the package-level functions.
+func defaultClient() *Client {
+	parsedURL, _ := url.Parse(defaultEndpoint)
+	hc := httpClient
+	if hc == nil {
+		hc = &http.Client{Timeout: defaultHTTPTimeout}
+	}
 	return &Client{
-		URL:             parsedURL,
-		httpClient:      httpClient,
-		authTokenHeader: authToken,
-		areaID:          areaID,
-	}, nil
+		URL:        parsedURL,
+		httpClient: hc,
+	}
 }
 
 // Jar returns the cookieJar.
@@ -95,33 +119,48 @@ func (c *Client) SetJar(jar *cookiejar.Jar) {
 
 // AreaID returns the areaID.
 func (c *Client) AreaID() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.areaID
 }
 
 // SetAreaID sets the areaID.
 func (c *Client) SetAreaID(areaID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.areaID = areaID
 }
 
 // AuthToken returns the authtoken.
 func (c *Client) AuthToken() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.authTokenHeader
 }
 
 func (c *Client) setAuthTokenHeader(authToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.authTokenHeader = authToken
 }
 
 func (c *Client) newRequest(ctx context.Context, verb, apiEndpoint string, params *Params) (*http.Request, error) {
 	u := *c.URL
 	u.Path = path.Join(c.URL.Path, apiEndpoint)
+	return c.newRequestURL(ctx, verb, &u, params)
+}
 
+// newRequestURL returns a request to the absolute url,
+// which is used for the endpoints outside of the API (e.g. m3u8).
+func (c *Client) newRequestURL(ctx context.Context, verb string, u *url.URL, params *Params) (*http.Request, error) {
 	// Add query parameters
-	urlQuery := u.Query()
-	for k, v := range params.query {
-		urlQuery.Set(k, v)
+	if len(params.query) > 0 {
+		urlQuery := u.Query()
+		for k, v := range params.query {
+			urlQuery.Set(k, v)
+		}
+		u.RawQuery = urlQuery.Encode()
 	}
-	u.RawQuery = urlQuery.Encode()
 
 	req, err := http.NewRequest(verb, u.String(), params.body)
 	if err != nil {
@@ -134,11 +173,11 @@ func (c *Client) newRequest(ctx context.Context, verb, apiEndpoint string, param
 	}
 	req = req.WithContext(ctx)
 
-	// Add request headers
+	// Add request headers, which may override the User-Agent
+	req.Header.Set("User-Agent", userAgent)
 	for k, v := range params.header {
 		req.Header.Set(k, v)
 	}
-	req.Header.Set("User-Agent", userAgent)
 	// For backwards compatibility with HTTP/1.0
 	// https://tools.ietf.org/html/rfc7234#page-29
 	req.Header.Set("pragma", "no-cache")
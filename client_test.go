@@ -4,6 +4,8 @@ import (
 	"context"
 	"net/http"
 	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -220,3 +222,17 @@ func TestAPIPath(t *testing.T) {
 		t.Errorf("invalid apiEndpoint: %s", apiEndpoint)
 	}
 }
+
+// newTestClient returns a Client whose endpoint is the test server.
+// It does not access radiko.jp to detect the area.
+func newTestClient(t *testing.T, ts *httptest.Server) *Client {
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Client{
+		URL:        u,
+		httpClient: ts.Client(),
+		areaID:     areaIDTokyo,
+	}
+}
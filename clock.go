@@ -0,0 +1,43 @@
+package radiko
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// srvtimeResolution is the resolution of <srvtime>.
+const srvtimeResolution = time.Second
+
+// ClockOffset returns the estimated offset of radiko's clock from the local clock.
+// It is updated from <srvtime> of every program guide response,
+// and is zero until a guide is fetched.
+func (c *Client) ClockOffset() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clockOffset
+}
+
+// Now returns the current time of radiko, which is the local time corrected by ClockOffset.
+func (c *Client) Now() time.Time {
+	return time.Now().Add(c.ClockOffset())
+}
+
+// updateClockOffset estimates the clock offset from the response
+// of the request sent and received at the given local times.
+func (c *Client) updateClockOffset(d *stationsData, header http.Header, sent, received time.Time) {
+	if d.Srvtime <= 0 {
+		return
+	}
+	// srvtime is truncated to seconds at some point during the round trip.
+	server := time.Unix(d.Srvtime, 0).Add(srvtimeResolution / 2)
+	// A cached response was generated Age seconds before.
+	if age, err := strconv.Atoi(header.Get("Age")); err == nil && age > 0 {
+		server = server.Add(time.Duration(age) * time.Second)
+	}
+	local := sent.Add(received.Sub(sent) / 2)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clockOffset = server.Sub(local)
+}
@@ -0,0 +1,48 @@
+package radiko
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_ClockOffset(t *testing.T) {
+	const skew = time.Hour
+	age := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if age != "" {
+			w.Header().Set("Age", age)
+		}
+		fmt.Fprintf(w, `<radiko><ttl>300</ttl><srvtime>%d</srvtime><stations></stations></radiko>`,
+			time.Now().Add(skew).Unix()-30)
+	}))
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	if client.ClockOffset() != 0 {
+		t.Errorf("expected 0, but %s", client.ClockOffset())
+	}
+
+	if _, err := client.GetNowPrograms(context.Background()); err != nil {
+		t.Fatalf("Failed to get programs: %s", err)
+	}
+	expected := skew - 30*time.Second
+	if d := client.ClockOffset() - expected; d < -time.Second || d > time.Second {
+		t.Errorf("expected about %s, but %s", expected, client.ClockOffset())
+	}
+	if d := client.Now().Sub(time.Now()) - expected; d < -time.Second || d > time.Second {
+		t.Errorf("Now should be corrected: %s", client.Now())
+	}
+
+	// The response was cached for 30 seconds.
+	age = "30"
+	if _, err := client.GetStations(context.Background(), time.Now()); err != nil {
+		t.Fatalf("Failed to get stations: %s", err)
+	}
+	if d := client.ClockOffset() - skew; d < -time.Second || d > time.Second {
+		t.Errorf("expected about %s, but %s", skew, client.ClockOffset())
+	}
+}
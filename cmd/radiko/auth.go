@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+func runAuth(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("auth")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+
+	client, err := cfg.authorizedClient(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.format == formatJSON {
+		return writeJSON(cfg.stdout, map[string]string{
+			"auth_token": client.AuthToken(),
+			"area_id":    client.AreaID(),
+		})
+	}
+	_, err = fmt.Fprintf(cfg.stdout, "auth_token: %s\narea_id: %s\n", client.AuthToken(), client.AreaID())
+	return err
+}
+
+func runLogin(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("login")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	status, err := cfg.login(ctx, client)
+	if err != nil {
+		return err
+	}
+	// Cache the auth_token enabled for the premium member.
+	if err := cfg.authorize(ctx, client); err != nil {
+		return err
+	}
+
+	if cfg.format == formatJSON {
+		return writeJSON(cfg.stdout, map[string]string{
+			"mail":        cfg.mail,
+			"paid_member": status.PaidMember,
+			"areafree":    status.Areafree,
+			"area_id":     client.AreaID(),
+		})
+	}
+	_, err = fmt.Fprintf(cfg.stdout, "logged in as %s (paid_member: %s, areafree: %s, area_id: %s)\n",
+		cfg.mail, status.PaidMember, status.Areafree, client.AreaID())
+	return err
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+const (
+	envMail     = "RADIKO_MAIL"
+	envPassword = "RADIKO_PASSWORD"
+)
+
+// config is shared by all commands.
+type config struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	format   string
+	areaID   string
+	mail     string
+	password string
+	cacheDir string
+	noCache  bool
+
+	timefree30 bool
+}
+
+func newConfig(stdout, stderr io.Writer) *config {
+	cacheDir := ""
+	if dir, err := os.UserCacheDir(); err == nil {
+		cacheDir = filepath.Join(dir, "go-radiko")
+	}
+	return &config{
+		stdout:   stdout,
+		stderr:   stderr,
+		cacheDir: cacheDir,
+	}
+}
+
+// flagSet returns a FlagSet which has the common flags.
+func (c *config) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("radiko "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.StringVar(&c.format, "format", "table", "output format: table or json")
+	fs.StringVar(&c.areaID, "area", "", "area id (e.g. JP13), detected if empty")
+	fs.StringVar(&c.mail, "mail", os.Getenv(envMail), "premium member's mail address ($"+envMail+")")
+	fs.StringVar(&c.password, "password", os.Getenv(envPassword), "premium member's password ($"+envPassword+")")
+	fs.StringVar(&c.cacheDir, "cache-dir", c.cacheDir, "directory to cache the auth_token and program guides")
+	fs.BoolVar(&c.noCache, "no-cache", false, "do not use the cached auth_token and program guides")
+	fs.BoolVar(&c.timefree30, "timefree30", false, "use timefree 30 of the area-free premium member (requires -mail)")
+	return fs
+}
+
+// parse parses args and validates the common flags.
+func (c *config) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{err.Error()}
+	}
+	if fs.NArg() > 0 {
+		return &usageError{fmt.Sprintf("unexpected arguments: %v", fs.Args())}
+	}
+	if c.format != formatTable && c.format != formatJSON {
+		return &usageError{fmt.Sprintf("invalid format: %s", c.format)}
+	}
+	if c.timefree30 && c.mail == "" {
+		return &usageError{"mail is required for timefree30"}
+	}
+	return nil
+}
+
+// newClient returns a Client which is not authorized.
+func (c *config) newClient() (*radiko.Client, error) {
+	client, err := radiko.New("")
+	if err != nil {
+		return nil, err
+	}
+	if c.areaID != "" {
+		client.SetAreaID(c.areaID)
+	}
+	c.setAreafreePolicy(client)
+	c.setGuideCache(client)
+	return client, nil
+}
+
+// setGuideCache caches the program guides in the cache directory.
+func (c *config) setGuideCache(client *radiko.Client) {
+	if c.noCache || c.cacheDir == "" {
+		return
+	}
+	store := &radiko.DiskGuideStore{Dir: filepath.Join(c.cacheDir, "guide")}
+	client.SetGuideCache(radiko.NewGuideCache(store))
+}
+
+// setAreafreePolicy prefers area-free streams for the premium member.
+func (c *config) setAreafreePolicy(client *radiko.Client) {
+	if c.mail != "" {
+		client.SetAreafreePolicy(radiko.PreferAreafree)
+	}
+}
+
+// authorizedClient returns a Client which has an enabled auth_token.
+// The cached auth_token is used if it is still valid,
+// except for timefree 30 which needs the logged-in session.
+func (c *config) authorizedClient(ctx context.Context) (*radiko.Client, error) {
+	if !c.noCache && !c.timefree30 {
+		if t, ok := c.tokenCache().load(c.mail, c.areaID); ok {
+			client, err := radiko.New(t.AuthToken)
+			if err != nil {
+				return nil, err
+			}
+			client.SetAreaID(t.AreaID)
+			c.setAreafreePolicy(client)
+			c.setGuideCache(client)
+			return client, nil
+		}
+	}
+
+	client, err := c.newClient()
+	if err != nil {
+		return nil, err
+	}
+	if c.mail != "" {
+		if _, err := c.login(ctx, client); err != nil {
+			return nil, err
+		}
+	}
+	if c.timefree30 {
+		if err := client.EnableTimefree30(ctx); err != nil {
+			return nil, &authError{err}
+		}
+	}
+	if err := c.authorize(ctx, client); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// authorize enables an auth_token of the client and caches it.
+func (c *config) authorize(ctx context.Context, client *radiko.Client) error {
+	result, err := client.AuthorizeToken(ctx)
+	if err != nil {
+		return &authError{err}
+	}
+
+	t := newToken(result.AuthToken, client.AreaID(), c.mail)
+	if err := c.tokenCache().save(t); err != nil {
+		fmt.Fprintf(c.stderr, "radiko: failed to cache auth_token: %s\n", err)
+	}
+	return nil
+}
+
+// login logs in as the premium member.
+func (c *config) login(ctx context.Context, client *radiko.Client) (radiko.LoginOK, error) {
+	if c.mail == "" || c.password == "" {
+		return radiko.LoginOK{}, &usageError{"mail and password are required to login"}
+	}
+
+	status, err := client.Login(ctx, c.mail, c.password)
+	if err != nil {
+		return radiko.LoginOK{}, &authError{err}
+	}
+	ok, isOK := status.(radiko.LoginOK)
+	if !isOK {
+		return radiko.LoginOK{}, &authError{fmt.Errorf("invalid status code: %d", status.StatusCode())}
+	}
+	return ok, nil
+}
+
+func (c *config) tokenCache() *tokenCache {
+	if c.cacheDir == "" {
+		return &tokenCache{}
+	}
+	return &tokenCache{path: filepath.Join(c.cacheDir, "token.json")}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/epg"
+	"github.com/yyoshiki41/go-radiko/internal/util"
+)
+
+const dateLayout = "20060102"
+
+var errStationNotFound = errors.New("station not found")
+
+func runStations(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("stations")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	stations, err := client.GetStations(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	return writeStations(cfg.stdout, cfg.format, stations)
+}
+
+func runNow(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("now")
+	stationID := fs.String("id", "", "station id (e.g. LFR), all stations if empty")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	stations, err := client.GetNowPrograms(ctx)
+	if err != nil {
+		return err
+	}
+	stations, err = filterStations(stations, epg.Filter{StationIDs: stationIDs(*stationID)})
+	if err != nil {
+		return err
+	}
+	return writePrograms(cfg.stdout, cfg.format, stations)
+}
+
+func runGuide(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("guide")
+	stationID := fs.String("id", "", "station id (e.g. LFR)")
+	date := fs.String("date", "", "broadcast date in JST (YYYYMMDD), the station's week if empty and -id is set")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+
+	stations, err := fetchGuide(ctx, cfg, *stationID, *date)
+	if err != nil {
+		return err
+	}
+	stations, err = filterStations(stations, epg.Filter{StationIDs: stationIDs(*stationID)})
+	if err != nil {
+		return err
+	}
+	return writePrograms(cfg.stdout, cfg.format, stations)
+}
+
+func runSearch(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("search")
+	keyword := fs.String("keyword", "", "keyword to search (required)")
+	stationID := fs.String("id", "", "station id (e.g. LFR)")
+	date := fs.String("date", "", "broadcast date in JST (YYYYMMDD), the station's week if empty and -id is set")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+	if *keyword == "" {
+		return &usageError{"-keyword is required"}
+	}
+
+	stations, err := fetchGuide(ctx, cfg, *stationID, *date)
+	if err != nil {
+		return err
+	}
+	stations, err = filterStations(stations, epg.Filter{
+		StationIDs: stationIDs(*stationID),
+		Keyword:    *keyword,
+	})
+	if err != nil {
+		return err
+	}
+	return writePrograms(cfg.stdout, cfg.format, stations)
+}
+
+// fetchGuide returns the weekly programs of the station if date is empty,
+// otherwise the programs of the day in the area.
+func fetchGuide(ctx context.Context, cfg *config, stationID, date string) (radiko.Stations, error) {
+	client, err := cfg.newClient()
+	if err != nil {
+		return nil, err
+	}
+	if date == "" && stationID != "" {
+		return client.GetWeeklyPrograms(ctx, stationID)
+	}
+
+	t := time.Now()
+	if date != "" {
+		t, err = time.ParseInLocation(dateLayout, date, util.Location())
+		if err != nil {
+			return nil, &usageError{"invalid -date, use YYYYMMDD"}
+		}
+		// 05:00 is the beginning of the broadcast day.
+		t = t.Add(5 * time.Hour)
+	}
+	return client.GetStations(ctx, t)
+}
+
+// filterStations returns the stations which have the programs passing f.
+func filterStations(stations radiko.Stations, f epg.Filter) (radiko.Stations, error) {
+	var filtered radiko.Stations
+	found := false
+	for _, s := range stations {
+		if !f.MatchStation(s) {
+			continue
+		}
+		found = true
+
+		var progs []radiko.Prog
+		for _, p := range s.Programs() {
+			if f.MatchProg(p) {
+				progs = append(progs, p)
+			}
+		}
+		if len(progs) == 0 {
+			continue
+		}
+		filtered = append(filtered, radiko.Station{
+			ID:    s.ID,
+			Name:  s.Name,
+			Progs: radiko.Progs{Progs: progs},
+		})
+	}
+	if !found && len(f.StationIDs) > 0 {
+		return nil, errStationNotFound
+	}
+	return filtered, nil
+}
+
+func stationIDs(id string) []string {
+	if id == "" {
+		return nil
+	}
+	return []string{id}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/epg"
+)
+
+func TestFilterStations(t *testing.T) {
+	stations := radiko.Stations{
+		{ID: "LFR", Progs: radiko.Progs{Progs: []radiko.Prog{{Title: "ANN"}, {Title: "News"}}}},
+		{ID: "TBS", Scd: radiko.Scd{Progs: radiko.Progs{Progs: []radiko.Prog{{Title: "ANN"}}}}},
+	}
+
+	filtered, err := filterStations(stations, epg.Filter{Keyword: "ann"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 2 || len(filtered[0].Programs()) != 1 || len(filtered[1].Programs()) != 1 {
+		t.Errorf("unexpected stations: %v", filtered)
+	}
+
+	if _, err := filterStations(stations, epg.Filter{StationIDs: []string{"QRR"}}); err != errStationNotFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/icecast"
+)
+
+const envIcecastPassword = "ICECAST_PASSWORD"
+
+var protocols = map[string]icecast.Protocol{
+	"put":       icecast.ProtocolPUT,
+	"source":    icecast.ProtocolSOURCE,
+	"shoutcast": icecast.ProtocolShoutcast,
+}
+
+func runIcecast(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("icecast")
+	var sf streamFlags
+	sf.register(fs, false)
+	src := &icecast.Source{}
+	fs.StringVar(&src.Addr, "server", "localhost:8000", "host:port of the server")
+	fs.StringVar(&src.Mount, "mount", "", "mount point (default: /<id>.aac)")
+	fs.StringVar(&src.User, "user", "source", "source user")
+	fs.StringVar(&src.Password, "source-password", os.Getenv(envIcecastPassword), "source password ($"+envIcecastPassword+")")
+	protocol := fs.String("protocol", "put", "protocol: put, source (legacy Icecast) or shoutcast")
+	fs.BoolVar(&src.Public, "public", false, "list the stream in the server's directory")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+	if err := sf.validate(); err != nil {
+		return err
+	}
+	p, ok := protocols[*protocol]
+	if !ok {
+		return &usageError{fmt.Sprintf("invalid protocol: %s", *protocol)}
+	}
+	src.Protocol = p
+	if src.Mount == "" {
+		src.Mount = "/" + sf.stationID + ".aac"
+	}
+	src.Name = sf.stationID
+
+	client, err := cfg.authorizedClient(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cfg.stderr, "streaming %s to %s%s\n", sf.stationID, src.Addr, src.Mount)
+	err = icecast.NewStreamer(radiko.NewTokenManager(client), src).Stream(ctx, sf.stationID)
+	if errors.Is(err, icecast.ErrUnauthorized) {
+		return &authError{err}
+	}
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,151 @@
+// Command radiko is a command-line client for radiko.jp.
+//
+// Usage:
+//
+//	radiko <command> [flags]
+//
+// Run "radiko help" for the list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+// Exit codes.
+const (
+	exitOK       = 0
+	exitError    = 1
+	exitUsage    = 2
+	exitAuth     = 3
+	exitNotFound = 4
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, cfg *config, args []string) error
+}
+
+var commands = []command{
+	{"stations", "list stations in the area", runStations},
+	{"now", "show programs currently on the air", runNow},
+	{"guide", "show the program guide of a day or a station's week", runGuide},
+	{"search", "search programs by keyword", runSearch},
+	{"auth", "authorize a token and cache it", runAuth},
+	{"login", "login as a premium member", runLogin},
+	{"play", "play a live or timeshift stream with an external player", runPlay},
+	{"record", "record live streams of one or more stations (-id LFR,TBS)", runRecord},
+	{"timeshift", "record a timeshift program", runTimeshift},
+	{"url", "print a playable playlist url", runURL},
+	{"relay", "serve streams as plain AAC over HTTP", runRelay},
+	{"icecast", "push a live stream to an Icecast or SHOUTcast server", runIcecast},
+}
+
+// usageError is returned by commands when the arguments are invalid.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// authError is returned by commands when authorization fails.
+type authError struct {
+	err error
+}
+
+func (e *authError) Error() string {
+	return "authorization failed: " + e.err.Error()
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	cmd, ok := findCommand(args[0])
+	if !ok {
+		fmt.Fprintf(stderr, "radiko: unknown command %q\n\n", args[0])
+		usage(stderr)
+		return exitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := newConfig(stdout, stderr)
+	err := cmd.run(ctx, cfg, args[1:])
+	code := exitCode(err)
+	if code != exitOK && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(stderr, "radiko %s: %s\n", cmd.name, err)
+	}
+	return code
+}
+
+func exitCode(err error) int {
+	var uerr *usageError
+	var aerr *authError
+	var terr *radiko.TimefreeError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.As(err, &uerr):
+		return exitUsage
+	case errors.As(err, &aerr):
+		return exitAuth
+	case errors.Is(err, radiko.ErrProgramNotFound), errors.Is(err, errStationNotFound), errors.As(err, &terr):
+		return exitNotFound
+	}
+	return exitError
+}
+
+func findCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: radiko <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	sorted := make([]command, len(commands))
+	copy(sorted, commands)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+	for _, c := range sorted {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run \"radiko <command> -h\" for the flags of each command.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit codes:")
+	fmt.Fprintln(w, "  0  success")
+	fmt.Fprintln(w, "  1  error")
+	fmt.Fprintln(w, "  2  invalid usage")
+	fmt.Fprintln(w, "  3  authorization or login failed")
+	fmt.Fprintln(w, "  4  station or program not found")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+func TestRun_Usage(t *testing.T) {
+	cases := []struct {
+		args     []string
+		expected int
+	}{
+		{nil, exitUsage},
+		{[]string{"help"}, exitOK},
+		{[]string{"unknown"}, exitUsage},
+		{[]string{"now", "-format", "xml"}, exitUsage},
+		{[]string{"search"}, exitUsage},
+		{[]string{"record", "-d", "1m"}, exitUsage},
+		{[]string{"record", "-id", "LFR", "-o", "{unknown}.aac"}, exitUsage},
+		{[]string{"record", "-id", "LFR,TBS", "-o", "out.aac"}, exitUsage},
+		{[]string{"timeshift", "-id", "LFR", "-s", "20161112230000", "-o", "{title"}, exitUsage},
+		{[]string{"stations", "-h"}, exitOK},
+		{[]string{"url", "-timefree30", "-mail", ""}, exitUsage},
+	}
+	for _, c := range cases {
+		var stdout, stderr bytes.Buffer
+		if actual := run(c.args, &stdout, &stderr); c.expected != actual {
+			t.Errorf("%v: expected %d, but %d\n%s", c.args, c.expected, actual, stderr.String())
+		}
+	}
+}
+
+func TestUsage(t *testing.T) {
+	var b bytes.Buffer
+	usage(&b)
+	for _, c := range commands {
+		if !strings.Contains(b.String(), c.name) {
+			t.Errorf("usage does not contain %s", c.name)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected int
+	}{
+		{nil, exitOK},
+		{flag.ErrHelp, exitOK},
+		{errors.New("error"), exitError},
+		{&usageError{"usage"}, exitUsage},
+		{&authError{errors.New("auth")}, exitAuth},
+		{fmt.Errorf("wrapped: %w", radiko.ErrProgramNotFound), exitNotFound},
+		{errStationNotFound, exitNotFound},
+		{&radiko.TimefreeError{Status: radiko.TimefreeExpired}, exitNotFound},
+	}
+	for _, c := range cases {
+		if actual := exitCode(c.err); c.expected != actual {
+			t.Errorf("%v: expected %d, but %d", c.err, c.expected, actual)
+		}
+	}
+}
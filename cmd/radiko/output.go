@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+
+	displayLayout = "2006-01-02 15:04"
+)
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeStations writes the list of stations.
+func writeStations(w io.Writer, format string, stations radiko.Stations) error {
+	if format == formatJSON {
+		type station struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}
+		list := make([]station, 0, len(stations))
+		for _, s := range stations {
+			list = append(list, station{s.ID, s.Name})
+		}
+		return writeJSON(w, list)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME")
+	for _, s := range stations {
+		fmt.Fprintf(tw, "%s\t%s\n", s.ID, s.Name)
+	}
+	return tw.Flush()
+}
+
+// writePrograms writes the programs of the stations.
+func writePrograms(w io.Writer, format string, stations radiko.Stations) error {
+	if format == formatJSON {
+		if stations == nil {
+			stations = radiko.Stations{}
+		}
+		return writeJSON(w, stations)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATION\tSTART\tEND\tTITLE")
+	for _, s := range stations {
+		for _, p := range s.Programs() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n",
+				s.ID, displayTime(p.StartTime()), displayTime(p.EndTime()), p.Title)
+		}
+	}
+	return tw.Flush()
+}
+
+func displayTime(t time.Time, err error) string {
+	if err != nil {
+		return "-"
+	}
+	return t.Format(displayLayout)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/hlsproxy"
+	"github.com/yyoshiki41/go-radiko/relay"
+)
+
+func runRelay(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("relay")
+	addr := fs.String("addr", ":8080", "address to listen on")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+
+	client, err := cfg.newClient()
+	if err != nil {
+		return err
+	}
+	if cfg.mail != "" {
+		if _, err := cfg.login(ctx, client); err != nil {
+			return err
+		}
+	}
+
+	tokens := radiko.NewTokenManager(client)
+	proxy := hlsproxy.New(tokens)
+	proxy.BasePath = "/hls"
+	mux := http.NewServeMux()
+	mux.Handle("/", relay.New(tokens))
+	mux.Handle("/hls/", proxy)
+
+	srv := &http.Server{Addr: *addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	fmt.Fprintf(cfg.stderr, "relay listening on %s (/live/{stationID}, /timeshift/{stationID}/{ft}, /hls/live/{stationID}/playlist.m3u8, /hls/timeshift/{stationID}/{ft}/playlist.m3u8)\n", *addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/filename"
+	"github.com/yyoshiki41/go-radiko/hlsproxy"
+	"github.com/yyoshiki41/go-radiko/id3"
+	"github.com/yyoshiki41/go-radiko/internal/hls"
+	"github.com/yyoshiki41/go-radiko/internal/util"
+	"github.com/yyoshiki41/go-radiko/recorder"
+)
+
+// The default templates of the output files of record and timeshift.
+const (
+	recordOutput    = "{station}_{date}{time}.aac"
+	timeshiftOutput = "{station}_{ft}.aac"
+)
+
+// streamFlags are the flags to select a live or timeshift stream.
+type streamFlags struct {
+	stationID string
+	start     string
+}
+
+func (f *streamFlags) register(fs *flag.FlagSet, timeshift bool) {
+	fs.StringVar(&f.stationID, "id", "", "station id (e.g. LFR, required)")
+	if timeshift {
+		fs.StringVar(&f.start, "s", "", "a time during the program in JST (YYYYMMDDhhmmss)")
+	}
+}
+
+func (f *streamFlags) validate() error {
+	if f.stationID == "" {
+		return &usageError{"-id is required"}
+	}
+	return nil
+}
+
+// program returns the program on the air at the time given by -s.
+// In timefree 30, it is looked up in the guide of the station,
+// which may be in another area.
+func (f *streamFlags) program(ctx context.Context, client *radiko.Client) (radiko.Station, radiko.Prog, error) {
+	at, err := util.ParseDatetime(f.start)
+	if err != nil {
+		return radiko.Station{}, radiko.Prog{}, &usageError{"invalid -s, use YYYYMMDDhhmmss (JST)"}
+	}
+
+	var stations radiko.Stations
+	if client.Timefree30() {
+		stations, err = client.GetStationPrograms(ctx, f.stationID, at)
+	} else {
+		stations, err = client.GetStations(ctx, at)
+	}
+	if err != nil {
+		return radiko.Station{}, radiko.Prog{}, err
+	}
+	for _, s := range stations {
+		if s.ID != f.stationID {
+			continue
+		}
+		for _, p := range s.Programs() {
+			ft, err := p.StartTime()
+			if err != nil {
+				continue
+			}
+			to, err := p.EndTime()
+			if err != nil {
+				continue
+			}
+			if !at.Before(ft) && at.Before(to) {
+				return s, p, nil
+			}
+		}
+		return radiko.Station{}, radiko.Prog{}, radiko.ErrProgramNotFound
+	}
+	return radiko.Station{}, radiko.Prog{}, errStationNotFound
+}
+
+// playlistURL returns the playlist url of the live or timeshift stream.
+func (f *streamFlags) playlistURL(ctx context.Context, client *radiko.Client) (string, error) {
+	if f.start == "" {
+		return client.LivePlaylistM3U8(ctx, f.stationID)
+	}
+
+	_, prog, err := f.program(ctx, client)
+	if err != nil {
+		return "", err
+	}
+	start, err := prog.StartTime()
+	if err != nil {
+		return "", err
+	}
+	return client.TimeshiftPlaylistM3U8(ctx, f.stationID, start)
+}
+
+// proxyPath returns the path of the live or timeshift stream on hlsproxy.
+func (f *streamFlags) proxyPath(ctx context.Context, client *radiko.Client, p *hlsproxy.Proxy) (string, error) {
+	if f.start == "" {
+		return p.LivePath(f.stationID), nil
+	}
+
+	_, prog, err := f.program(ctx, client)
+	if err != nil {
+		return "", err
+	}
+	return p.TimeshiftPath(f.stationID, prog.Ft), nil
+}
+
+func runURL(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("url")
+	var sf streamFlags
+	sf.register(fs, true)
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+	if err := sf.validate(); err != nil {
+		return err
+	}
+
+	client, err := cfg.authorizedClient(ctx)
+	if err != nil {
+		return err
+	}
+	uri, err := sf.playlistURL(ctx, client)
+	if err != nil {
+		return err
+	}
+	if cfg.format == formatJSON {
+		return writeJSON(cfg.stdout, map[string]string{"url": uri})
+	}
+	_, err = fmt.Fprintln(cfg.stdout, uri)
+	return err
+}
+
+func runPlay(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("play")
+	var sf streamFlags
+	sf.register(fs, true)
+	playerCmd := fs.String("player", "ffplay", "player command")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+	if err := sf.validate(); err != nil {
+		return err
+	}
+
+	client, err := cfg.authorizedClient(ctx)
+	if err != nil {
+		return err
+	}
+	// Players can not send the auth_token, so the stream is served by a local proxy.
+	proxy := hlsproxy.New(radiko.NewTokenManager(client))
+	proxyPath, err := sf.proxyPath(ctx, client, proxy)
+	if err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: proxy}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	uri := "http://" + ln.Addr().String() + proxyPath
+	cmd := exec.CommandContext(ctx, *playerCmd, uri)
+	cmd.Stdout = cfg.stderr
+	cmd.Stderr = cfg.stderr
+	return cmd.Run()
+}
+
+func runRecord(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("record")
+	var sf streamFlags
+	sf.register(fs, false)
+	duration := fs.Duration("d", 30*time.Minute, "recording duration")
+	output := fs.String("o", recordOutput, "output file, which may be a template of {station}, {date}, {time}, etc.")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+	if err := sf.validate(); err != nil {
+		return err
+	}
+	if *duration <= 0 {
+		return &usageError{"-d must be positive"}
+	}
+	tmpl, err := filename.Parse(*output)
+	if err != nil {
+		return &usageError{err.Error()}
+	}
+	if ids := strings.Split(sf.stationID, ","); len(ids) > 1 {
+		if !strings.Contains(*output, "{station}") {
+			return &usageError{"-o must contain {station} with several stations"}
+		}
+		return recordStations(ctx, cfg, ids, *output, *duration)
+	}
+
+	client, err := cfg.authorizedClient(ctx)
+	if err != nil {
+		return err
+	}
+	uri, err := sf.playlistURL(ctx, client)
+	if err != nil {
+		return err
+	}
+	path := filepath.FromSlash(tmpl.Execute(filename.Data{
+		Station: radiko.Station{ID: sf.stationID},
+		Time:    client.Now(),
+	}))
+	err = record(ctx, client, uri, path, hls.Options{Duration: *duration})
+	if interrupted(err) {
+		return nil
+	}
+	return err
+}
+
+// recordStations records the live streams of the stations at once
+// into the files of the template.
+func recordStations(ctx context.Context, cfg *config, ids []string, output string, d time.Duration) error {
+	client, err := cfg.authorizedClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	r := recorder.NewMultiRecorder(radiko.NewTokenManager(client), ids...)
+	r.Start = client.Now()
+	r.End = r.Start.Add(d)
+	r.Sink = &recorder.FileSink{Path: output}
+	err = r.Record(ctx)
+	for _, p := range r.Progress() {
+		fmt.Fprintf(cfg.stderr, "%s: %d bytes, %d retries\n", p.StationID, p.Bytes, p.Retries)
+	}
+	if interrupted(err) {
+		return nil
+	}
+	return err
+}
+
+// interrupted reports whether the recording stopped by ctx, e.g. by Ctrl-C.
+// The recorded part is kept, so that it is not an error.
+func interrupted(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func runTimeshift(ctx context.Context, cfg *config, args []string) error {
+	fs := cfg.flagSet("timeshift")
+	var sf streamFlags
+	sf.register(fs, true)
+	output := fs.String("o", timeshiftOutput, "output file, which may be a template of {station}, {ft}, {title}, etc.")
+	tag := fs.Bool("id3", false, "write ID3 tags of the program")
+	if err := cfg.parse(fs, args); err != nil {
+		return err
+	}
+	if err := sf.validate(); err != nil {
+		return err
+	}
+	if sf.start == "" {
+		return &usageError{"-s is required"}
+	}
+	tmpl, err := filename.Parse(*output)
+	if err != nil {
+		return &usageError{err.Error()}
+	}
+
+	client, err := cfg.authorizedClient(ctx)
+	if err != nil {
+		return err
+	}
+	station, prog, err := sf.program(ctx, client)
+	if err != nil {
+		return err
+	}
+	start, err := prog.StartTime()
+	if err != nil {
+		return err
+	}
+	uri, err := client.TimeshiftPlaylistM3U8(ctx, sf.stationID, start)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.FromSlash(tmpl.Execute(filename.Data{Station: station, Prog: prog}))
+	if err := record(ctx, client, uri, path, hls.Options{}); err != nil {
+		return err
+	}
+	if !*tag {
+		return nil
+	}
+	t, err := id3.FromProgram(station, prog)
+	if err != nil {
+		return err
+	}
+	return id3.WriteFile(path, t)
+}
+
+// record writes the segments of the playlist to the file,
+// creating the directory of the file if needed.
+func record(ctx context.Context, client *radiko.Client, uri, path string, opts hls.Options) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	// A long recording outlives the auth_token, which the fetcher refreshes.
+	err = hls.NewFetcher(radiko.NewTokenManager(client)).Copy(ctx, f, uri, opts)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
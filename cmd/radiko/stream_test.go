@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTimefree30TestServer returns a server of a premium member in JP13,
+// where HBC of JP1 is not in the guide of the area.
+func newTimefree30TestServer(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch p := r.URL.Path; {
+		case p == "/area":
+			fmt.Fprint(w, `document.write('<span class="JP13">TOKYO JAPAN</span>');`)
+		case p == "/ap/member/webapi/member/login":
+		case p == "/ap/member/webapi/member/login/check":
+			fmt.Fprint(w, `{"status":"200","user_key":"key","paid_member":"1","areafree":"1"}`)
+		case strings.HasPrefix(p, "/v3/program/date/"):
+			fmt.Fprint(w, `<radiko><stations><station id="LFR"><name>ニッポン放送</name><progs>`+
+				`<prog ft="20161112230000" to="20161112233000"><title>LFR</title></prog>`+
+				`</progs></station></stations></radiko>`)
+		case p == "/v3/program/station/date/20161112/HBC.xml":
+			fmt.Fprint(w, `<radiko><stations><station id="HBC"><name>HBCラジオ</name><progs>`+
+				`<prog ft="20161112230000" to="20161112233000"><title>HBC</title></prog>`+
+				`</progs></station></stations></radiko>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	radiko.SetHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.URL.Scheme, req.URL.Host = u.Scheme, u.Host
+		return http.DefaultTransport.RoundTrip(req)
+	})})
+	return ts
+}
+
+func TestStreamFlags_Program_Timefree30(t *testing.T) {
+	ts := newTimefree30TestServer(t)
+	defer ts.Close()
+	defer radiko.SetHTTPClient(&http.Client{Timeout: 120 * time.Second})
+
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatalf("Failed to construct client: %s", err)
+	}
+	ctx := context.Background()
+	sf := streamFlags{stationID: "HBC", start: "20161112231500"}
+	if _, _, err := sf.program(ctx, client); err != errStationNotFound {
+		t.Errorf("expected %v, but %v", errStationNotFound, err)
+	}
+
+	if _, err := client.Login(ctx, "mail", "password"); err != nil {
+		t.Fatalf("Failed to login: %s", err)
+	}
+	if err := client.EnableTimefree30(ctx); err != nil {
+		t.Fatalf("Failed to enable timefree 30: %s", err)
+	}
+	station, prog, err := sf.program(ctx, client)
+	if err != nil {
+		t.Fatalf("Failed to get the program: %s", err)
+	}
+	if station.ID != "HBC" || prog.Title != "HBC" {
+		t.Errorf("unexpected program: %s, %+v", station.ID, prog)
+	}
+}
+
+func TestInterrupted(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected bool
+	}{
+		{context.Canceled, true},
+		{context.DeadlineExceeded, true},
+		{fmt.Errorf("hls: %w", context.Canceled), true},
+		{errors.New("error"), false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		if actual := interrupted(c.err); actual != c.expected {
+			t.Errorf("%v: expected %v, but %v", c.err, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// tokenTTL is shorter than the lifetime of radiko's auth_token (about 70 minutes).
+const tokenTTL = 60 * time.Minute
+
+// token is a cached auth_token.
+type token struct {
+	AuthToken string    `json:"auth_token"`
+	AreaID    string    `json:"area_id"`
+	Mail      string    `json:"mail,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func newToken(authToken, areaID, mail string) token {
+	return token{
+		AuthToken: authToken,
+		AreaID:    areaID,
+		Mail:      mail,
+		ExpiresAt: time.Now().Add(tokenTTL),
+	}
+}
+
+// tokenCache stores a token in a file.
+// The zero value is a cache which stores nothing.
+type tokenCache struct {
+	path string
+}
+
+// load returns the cached token if it is valid for the given mail and area.
+func (c *tokenCache) load(mail, areaID string) (token, bool) {
+	if c.path == "" {
+		return token{}, false
+	}
+	b, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return token{}, false
+	}
+
+	var t token
+	if err := json.Unmarshal(b, &t); err != nil {
+		return token{}, false
+	}
+	if t.AuthToken == "" || t.Mail != mail || time.Now().After(t.ExpiresAt) {
+		return token{}, false
+	}
+	if areaID != "" && t.AreaID != areaID {
+		return token{}, false
+	}
+	return t, true
+}
+
+func (c *tokenCache) save(t token) error {
+	if c.path == "" {
+		return nil
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, b, 0600)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTokenCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-go-radiko-cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &tokenCache{path: filepath.Join(dir, "cache", "token.json")}
+	if _, ok := c.load("", ""); ok {
+		t.Error("Should not load a missing token.")
+	}
+
+	if err := c.save(newToken("token", "JP13", "")); err != nil {
+		t.Fatal(err)
+	}
+	if tk, ok := c.load("", ""); !ok || tk.AuthToken != "token" {
+		t.Errorf("unexpected token: %v", tk)
+	}
+	if _, ok := c.load("", "JP27"); ok {
+		t.Error("Should not load a token of another area.")
+	}
+	if _, ok := c.load("example@mail.com", ""); ok {
+		t.Error("Should not load a token of another member.")
+	}
+
+	expired := newToken("token", "JP13", "")
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	if err := c.save(expired); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.load("", ""); ok {
+		t.Error("Should not load an expired token.")
+	}
+}
+
+func TestTokenCache_Empty(t *testing.T) {
+	c := &tokenCache{}
+	if err := c.save(newToken("token", "JP13", "")); err != nil {
+		t.Error(err)
+	}
+	if _, ok := c.load("", ""); ok {
+		t.Error("Should not load a token.")
+	}
+}
@@ -0,0 +1,81 @@
+package radiko
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/yyoshiki41/go-radiko/htmltext"
+)
+
+// csvHeader is the header row written by WriteCSV.
+var csvHeader = []string{
+	"station_id",
+	"station_name",
+	"start",
+	"end",
+	"duration",
+	"title",
+	"sub_title",
+	"performers",
+	"description",
+	"url",
+}
+
+// WriteCSV writes one row per program with a header row.
+// Times are formatted in RFC 3339 (JST), durations in seconds,
+// and descriptions are converted into plain text.
+func WriteCSV(w io.Writer, stations Stations) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, s := range stations {
+		for _, p := range s.Programs() {
+			record, err := csvRecord(s, p)
+			if err != nil {
+				return err
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvRecord(s Station, p Prog) ([]string, error) {
+	start, err := p.StartTime()
+	if err != nil {
+		return nil, err
+	}
+	end, err := p.EndTime()
+	if err != nil {
+		return nil, err
+	}
+	d, err := p.Duration()
+	if err != nil {
+		return nil, err
+	}
+
+	desc := htmltext.PlainText(p.Desc)
+	if desc == "" {
+		desc = htmltext.PlainText(p.Info)
+	}
+
+	return []string{
+		s.ID,
+		s.Name,
+		start.Format(time.RFC3339),
+		end.Format(time.RFC3339),
+		strconv.FormatInt(int64(d/time.Second), 10),
+		p.Title,
+		p.SubTitle,
+		p.Pfm,
+		desc,
+		p.URL,
+	}, nil
+}
@@ -0,0 +1,29 @@
+package radiko
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+)
+
+func TestWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteCSV(&b, loadTestStations(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected 4 records, but %d", len(records))
+	}
+	if records[0][0] != "station_id" {
+		t.Errorf("unexpected header: %v", records[0])
+	}
+	r := records[2]
+	if r[0] != "LFR" || r[2] != "2016-11-12T23:00:00+09:00" || r[4] != "1800" {
+		t.Errorf("unexpected record: %v", r)
+	}
+}
@@ -0,0 +1,113 @@
+package radiko
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// DeviceProfile is the device which an app of radiko identifies itself as
+// in the X-Radiko-* headers.
+type DeviceProfile struct {
+	// App is sent as X-Radiko-App, e.g. "pc_html5" or "aSmartPhone7a".
+	App string
+	// AppVersion is sent as X-Radiko-App-Version.
+	AppVersion string
+	// Device is sent as X-Radiko-Device, e.g. "pc" or "30.Pixel_5" ("{API level}.{model}").
+	Device string
+	// User is sent as X-Radiko-User.
+	// If empty, a random user ID is generated once per Client.
+	User string
+	// UserAgent overrides the User-Agent header if not empty.
+	UserAgent string
+}
+
+// HTML5Device returns the profile of radiko's HTML5 player, which is used by default.
+func HTML5Device() DeviceProfile {
+	return DeviceProfile{
+		App:        "pc_html5",
+		AppVersion: "0.0.1",
+		Device:     "pc",
+		User:       "test-stream",
+	}
+}
+
+// AndroidDevice returns the profile of radiko's Android app.
+func AndroidDevice() DeviceProfile {
+	return DeviceProfile{
+		App:        "aSmartPhone7a",
+		AppVersion: "7.5.0",
+		Device:     "30.Pixel_5",
+		UserAgent:  "Dalvik/2.1.0 (Linux; U; Android 11; Pixel 5 Build/RQ3A.211001.001)",
+	}
+}
+
+// header returns the request headers which identify the device.
+func (d DeviceProfile) header() map[string]string {
+	h := map[string]string{
+		radikoAppHeader:        d.App,
+		radikoAppVersionHeader: d.AppVersion,
+		radikoUserHeader:       d.User,
+		radikoDeviceHeader:     d.Device,
+	}
+	if d.UserAgent != "" {
+		h["User-Agent"] = d.UserAgent
+	}
+	return h
+}
+
+// randomUserID returns a random user ID in the format of the apps.
+func randomUserID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// DeviceProfile returns the profile sent by the requests with X-Radiko-* headers,
+// i.e. Auth1, Auth2 and the playlist requests.
+func (c *Client) DeviceProfile() DeviceProfile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.deviceProfile == nil {
+		return HTML5Device()
+	}
+	return *c.deviceProfile
+}
+
+// SetDeviceProfile sets the profile sent by the requests with X-Radiko-* headers.
+// If d.User is empty, a random user ID is generated for the client.
+func (c *Client) SetDeviceProfile(d DeviceProfile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deviceProfile = &d
+}
+
+// setDefaultDeviceProfile sets the profile unless a profile has been set.
+func (c *Client) setDefaultDeviceProfile(d DeviceProfile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deviceProfile == nil {
+		c.deviceProfile = &d
+	}
+}
+
+// device returns the profile with the user ID, which is generated once per Client if not given.
+func (c *Client) device() (DeviceProfile, error) {
+	d := c.DeviceProfile()
+	if d.User != "" {
+		return d, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.userID == "" {
+		user, err := randomUserID()
+		if err != nil {
+			return d, err
+		}
+		c.userID = user
+	}
+	d.User = c.userID
+	return d, nil
+}
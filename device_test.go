@@ -0,0 +1,68 @@
+package radiko
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_DeviceProfile(t *testing.T) {
+	var headers []http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		switch r.URL.Path {
+		case "/v2/api/auth1":
+			w.Header().Set(radikoAuthTokenHeader, "token")
+			w.Header().Set(radikoKeyLentghHeader, "16")
+			w.Header().Set(radikoKeyOffsetHeader, "0")
+		case "/v2/api/auth2":
+			w.Write([]byte("JP13,東京都,tokyo Japan"))
+		default:
+			w.Write([]byte("#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=52973\nhttps://example.com/chunklist.m3u8\n"))
+		}
+	}))
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	if d := client.DeviceProfile(); d != HTML5Device() {
+		t.Errorf("unexpected default profile: %+v", d)
+	}
+	client.SetDeviceProfile(DeviceProfile{
+		App:        "pc_html5",
+		AppVersion: "9.9.9",
+		Device:     "pc",
+		UserAgent:  "test-agent",
+	})
+
+	ctx := context.Background()
+	if _, err := client.AuthorizeToken(ctx); err != nil {
+		t.Fatalf("Failed to authorize: %s", err)
+	}
+	if _, err := client.requestTimeshiftPlaylistURI(ctx, "POST", ts.URL+"/tf/playlist.m3u8"); err != nil {
+		t.Fatalf("Failed to request the playlist: %s", err)
+	}
+
+	if len(headers) != 3 {
+		t.Fatalf("expected 3 requests, but %d", len(headers))
+	}
+	user := headers[0].Get(radikoUserHeader)
+	if len(user) != 32 {
+		t.Errorf("Should generate a random user: %s", user)
+	}
+	for _, h := range headers {
+		if h.Get(radikoAppVersionHeader) != "9.9.9" || h.Get(radikoAppHeader) != "pc_html5" {
+			t.Errorf("unexpected app: %s %s", h.Get(radikoAppHeader), h.Get(radikoAppVersionHeader))
+		}
+		if h.Get(radikoUserHeader) != user {
+			t.Errorf("Should keep the user per client: %s, %s", user, h.Get(radikoUserHeader))
+		}
+		if h.Get("User-Agent") != "test-agent" {
+			t.Errorf("unexpected User-Agent: %s", h.Get("User-Agent"))
+		}
+	}
+
+	if other, err := newTestClient(t, ts).device(); err != nil || other.User != "test-stream" {
+		t.Errorf("unexpected user of the default profile: %s, %v", other.User, err)
+	}
+}
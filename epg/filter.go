@@ -0,0 +1,63 @@
+// Package epg exports radiko program guides to XMLTV and iCalendar.
+package epg
+
+import (
+	"strings"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+// Filter selects programs to export.
+// The zero value matches every program.
+type Filter struct {
+	// StationIDs restricts programs to the given stations.
+	StationIDs []string
+	// Keyword restricts programs to those whose title, performers or
+	// descriptions contain it (case-insensitive).
+	Keyword string
+}
+
+// MatchStation reports whether the station passes the filter.
+func (f Filter) MatchStation(s radiko.Station) bool {
+	if len(f.StationIDs) == 0 {
+		return true
+	}
+	for _, id := range f.StationIDs {
+		if id == s.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchProg reports whether the program passes the keyword filter.
+func (f Filter) MatchProg(p radiko.Prog) bool {
+	if f.Keyword == "" {
+		return true
+	}
+	keyword := strings.ToLower(f.Keyword)
+	for _, s := range []string{p.Title, p.SubTitle, p.Pfm, p.Desc, p.Info} {
+		if strings.Contains(strings.ToLower(s), keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// each calls fn for each program which passes the filter.
+func (f Filter) each(stations radiko.Stations, fn func(radiko.Station, radiko.Prog) error) error {
+	for _, s := range stations {
+		if !f.MatchStation(s) {
+			continue
+		}
+		for _, p := range s.Programs() {
+			if !f.MatchProg(p) {
+				continue
+			}
+			if err := fn(s, p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package epg
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+func loadTestStations(t *testing.T) radiko.Stations {
+	_, currentFile, _, _ := runtime.Caller(0)
+	b, err := ioutil.ReadFile(filepath.Join(filepath.Dir(currentFile), "..", "testdata", "stations.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var d struct {
+		Stations radiko.Stations `xml:"stations>station"`
+	}
+	if err := xml.Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	}
+	return d.Stations
+}
+
+func TestFilter(t *testing.T) {
+	stations := loadTestStations(t)
+
+	cases := []struct {
+		filter   Filter
+		expected int
+	}{
+		{Filter{}, 3},
+		{Filter{StationIDs: []string{"LFR"}}, 2},
+		{Filter{Keyword: "smap"}, 1},
+		{Filter{StationIDs: []string{"TBS"}, Keyword: "smap"}, 0},
+	}
+	for _, c := range cases {
+		var n int
+		c.filter.each(stations, func(radiko.Station, radiko.Prog) error {
+			n++
+			return nil
+		})
+		if n != c.expected {
+			t.Errorf("%+v: expected %d, but %d", c.filter, c.expected, n)
+		}
+	}
+}
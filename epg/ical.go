@@ -0,0 +1,85 @@
+package epg
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+const (
+	icalLayout = "20060102T150405Z"
+	// icalLineLength is the maximum length of a content line in octets.
+	icalLineLength = 75
+)
+
+var icalEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// WriteICalendar writes the programs which pass the filter in iCalendar format.
+// Each program is a VEVENT whose URL is its timeshift url.
+func WriteICalendar(w io.Writer, stations radiko.Stations, f Filter) error {
+	bw := bufio.NewWriter(w)
+	now := time.Now().UTC().Format(icalLayout)
+
+	writeICalLine(bw, "BEGIN:VCALENDAR")
+	writeICalLine(bw, "VERSION:2.0")
+	writeICalLine(bw, "PRODID:-//go-radiko//EPG//JA")
+	writeICalLine(bw, "CALSCALE:GREGORIAN")
+	err := f.each(stations, func(s radiko.Station, p radiko.Prog) error {
+		start, err := p.StartTime()
+		if err != nil {
+			return err
+		}
+		end, err := p.EndTime()
+		if err != nil {
+			return err
+		}
+
+		writeICalLine(bw, "BEGIN:VEVENT")
+		writeICalLine(bw, "UID:"+s.ID+"-"+p.Ft+"@radiko.jp")
+		writeICalLine(bw, "DTSTAMP:"+now)
+		writeICalLine(bw, "DTSTART:"+start.UTC().Format(icalLayout))
+		writeICalLine(bw, "DTEND:"+end.UTC().Format(icalLayout))
+		writeICalLine(bw, "SUMMARY:"+icalEscaper.Replace(p.Title))
+		writeICalLine(bw, "LOCATION:"+icalEscaper.Replace(s.Name))
+		if desc := description(p); desc != "" {
+			writeICalLine(bw, "DESCRIPTION:"+icalEscaper.Replace(desc))
+		}
+		writeICalLine(bw, "URL:"+radiko.GetTimeshiftURL(s.ID, start))
+		writeICalLine(bw, "END:VEVENT")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	writeICalLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// writeICalLine writes a content line folded at icalLineLength octets
+// without splitting UTF-8 sequences.
+func writeICalLine(w *bufio.Writer, line string) {
+	limit := icalLineLength
+	for len(line) > limit {
+		i := limit
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		w.WriteString(line[:i])
+		w.WriteString("\r\n ")
+		line = line[i:]
+		// The leading space of a continuation line counts.
+		limit = icalLineLength - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
@@ -0,0 +1,49 @@
+package epg
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteICalendar(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteICalendar(&b, loadTestStations(t), Filter{Keyword: "SMAP"}); err != nil {
+		t.Fatal(err)
+	}
+	s := b.String()
+
+	if !strings.HasPrefix(s, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(s, "END:VCALENDAR\r\n") {
+		t.Errorf("invalid calendar: %s", s)
+	}
+	if n := strings.Count(s, "BEGIN:VEVENT"); n != 1 {
+		t.Errorf("expected 1 event, but %d", n)
+	}
+	for _, expected := range []string{
+		"DTSTART:20161112T140000Z\r\n",
+		"URL:https://radiko.jp/#!/ts/LFR/20161112230000\r\n",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Errorf("missing %q", expected)
+		}
+	}
+	for _, line := range strings.Split(s, "\r\n") {
+		if len(line) > icalLineLength {
+			t.Errorf("line is too long: %q", line)
+		}
+	}
+}
+
+func TestWriteICalLine(t *testing.T) {
+	var b bytes.Buffer
+	w := bufio.NewWriter(&b)
+	line := "DESCRIPTION:" + strings.Repeat("あ", 50)
+	writeICalLine(w, line)
+	w.Flush()
+
+	unfolded := strings.Replace(strings.TrimSuffix(b.String(), "\r\n"), "\r\n ", "", -1)
+	if unfolded != line {
+		t.Errorf("expected %q, but %q", line, unfolded)
+	}
+}
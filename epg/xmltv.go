@@ -0,0 +1,113 @@
+package epg
+
+import (
+	"encoding/xml"
+	"io"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/htmltext"
+)
+
+const (
+	xmltvLayout   = "20060102150405 -0700"
+	xmltvLang     = "ja"
+	channelSuffix = ".radiko.jp"
+)
+
+// WriteXMLTV writes the programs which pass the filter in XMLTV format.
+func WriteXMLTV(w io.Writer, stations radiko.Stations, f Filter) error {
+	doc := xmltv{GeneratorName: "go-radiko"}
+	for _, s := range stations {
+		if !f.MatchStation(s) {
+			continue
+		}
+		doc.Channels = append(doc.Channels, xmltvChannel{
+			ID:          channelID(s),
+			DisplayName: xmltvText{Lang: xmltvLang, Value: s.Name},
+		})
+	}
+
+	err := f.each(stations, func(s radiko.Station, p radiko.Prog) error {
+		start, err := p.StartTime()
+		if err != nil {
+			return err
+		}
+		stop, err := p.EndTime()
+		if err != nil {
+			return err
+		}
+
+		prog := xmltvProgramme{
+			Start:   start.Format(xmltvLayout),
+			Stop:    stop.Format(xmltvLayout),
+			Channel: channelID(s),
+			Title:   xmltvText{Lang: xmltvLang, Value: p.Title},
+			URL:     p.URL,
+		}
+		if p.SubTitle != "" {
+			prog.SubTitle = &xmltvText{Lang: xmltvLang, Value: p.SubTitle}
+		}
+		if desc := description(p); desc != "" {
+			prog.Desc = &xmltvText{Lang: xmltvLang, Value: desc}
+		}
+		if p.Pfm != "" {
+			prog.Credits = &xmltvCredits{Presenter: p.Pfm}
+		}
+		doc.Programmes = append(doc.Programmes, prog)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+func channelID(s radiko.Station) string {
+	return s.ID + channelSuffix
+}
+
+// description returns the plain text description of the program.
+func description(p radiko.Prog) string {
+	if desc := htmltext.PlainText(p.Desc); desc != "" {
+		return desc
+	}
+	return htmltext.PlainText(p.Info)
+}
+
+type xmltv struct {
+	XMLName       xml.Name         `xml:"tv"`
+	GeneratorName string           `xml:"generator-info-name,attr"`
+	Channels      []xmltvChannel   `xml:"channel"`
+	Programmes    []xmltvProgramme `xml:"programme"`
+}
+
+type xmltvChannel struct {
+	ID          string    `xml:"id,attr"`
+	DisplayName xmltvText `xml:"display-name"`
+}
+
+type xmltvProgramme struct {
+	Start    string        `xml:"start,attr"`
+	Stop     string        `xml:"stop,attr"`
+	Channel  string        `xml:"channel,attr"`
+	Title    xmltvText     `xml:"title"`
+	SubTitle *xmltvText    `xml:"sub-title"`
+	Desc     *xmltvText    `xml:"desc"`
+	Credits  *xmltvCredits `xml:"credits"`
+	URL      string        `xml:"url,omitempty"`
+}
+
+type xmltvText struct {
+	Lang  string `xml:"lang,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type xmltvCredits struct {
+	Presenter string `xml:"presenter"`
+}
@@ -0,0 +1,32 @@
+package epg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestWriteXMLTV(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteXMLTV(&b, loadTestStations(t), Filter{StationIDs: []string{"LFR"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc xmltv
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Channels) != 1 || doc.Channels[0].ID != "LFR.radiko.jp" {
+		t.Errorf("unexpected channels: %v", doc.Channels)
+	}
+	if len(doc.Programmes) != 2 {
+		t.Fatalf("expected 2 programmes, but %d", len(doc.Programmes))
+	}
+	p := doc.Programmes[0]
+	if expected := "20161112230000 +0900"; p.Start != expected {
+		t.Errorf("expected %s, but %s", expected, p.Start)
+	}
+	if p.Desc == nil || bytes.Contains([]byte(p.Desc.Value), []byte("<br>")) {
+		t.Errorf("desc should be plain text: %v", p.Desc)
+	}
+}
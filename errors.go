@@ -1,8 +1,74 @@
 package radiko
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrProgramNotFound is returned when a program not found
 	ErrProgramNotFound = errors.New("program not found")
+	// ErrStopWalk is returned by the function passed to WalkPrograms
+	// to stop walking without an error
+	ErrStopWalk = errors.New("stop walking")
+	// ErrOutsideJapan is returned when auth2 authorizes no area of Japan,
+	// e.g. the response is "OUT" for the IP addresses outside of Japan
+	ErrOutsideJapan = errors.New("outside of Japan")
+	// ErrTimefree30NotAllowed is returned by EnableTimefree30
+	// when the logged-in session is not of an area-free premium member
+	ErrTimefree30NotAllowed = errors.New("timefree 30 is not allowed for the session")
 )
+
+// AuthStatusError is returned when auth1 or auth2 responds with a non-2xx status.
+type AuthStatusError struct {
+	// Op is "auth1" or "auth2".
+	Op         string
+	StatusCode int
+}
+
+func (e *AuthStatusError) Error() string {
+	return fmt.Sprintf("%s: unexpected status code: %d", e.Op, e.StatusCode)
+}
+
+// PlaylistStatusError is returned when a playlist create url responds with a non-2xx status.
+// radiko responds with 401 or 403 if it rejects the auth_token.
+type PlaylistStatusError struct {
+	// Op is the failed operation, e.g. "create live playlist".
+	Op         string
+	StatusCode int
+	// Body is the beginning of the response body.
+	Body string
+}
+
+func (e *PlaylistStatusError) Error() string {
+	return fmt.Sprintf("failed to %s: status=%d body=%q", e.Op, e.StatusCode, e.Body)
+}
+
+// AuthHeaderError is returned when auth1 responds without a required header,
+// or with an invalid value.
+type AuthHeaderError struct {
+	// Op is "auth1" or "auth2".
+	Op     string
+	Header string
+	// Value is the invalid value, which is empty if the header is missing.
+	Value string
+}
+
+func (e *AuthHeaderError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("%s: missing header: %s", e.Op, e.Header)
+	}
+	return fmt.Sprintf("%s: invalid header: %s=%q", e.Op, e.Header, e.Value)
+}
+
+// KeyRangeError is returned when the key range given by auth1 is out of the auth key.
+type KeyRangeError struct {
+	Offset    int64
+	Length    int64
+	KeyLength int
+}
+
+func (e *KeyRangeError) Error() string {
+	return fmt.Sprintf("key range is out of the auth key: offset=%d, length=%d, key length=%d",
+		e.Offset, e.Length, e.KeyLength)
+}
@@ -18,9 +18,9 @@ func main() {
 	// 2. Enables and sets the auth_token.
 	// After client.AuthorizeToken() has succeeded,
 	// the client has the enabled auth_token internally.
-	authToken, err := client.AuthorizeToken(context.Background())
+	result, err := client.AuthorizeToken(context.Background())
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Println(authToken)
+	fmt.Println(result.AuthToken, result.Area.ID, result.Area.Name)
 }
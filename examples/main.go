@@ -2,8 +2,9 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
 	"log"
+	"os"
 
 	"github.com/yyoshiki41/go-radiko"
 )
@@ -19,7 +20,12 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	fmt.Printf("%v", stations)
+	// Print stations data in JSON
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(stations); err != nil {
+		log.Fatal(err)
+	}
 
 	// if an auth_token is cached, set a token header like below.
 	client, err = radiko.New("auth_token")
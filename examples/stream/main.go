@@ -4,11 +4,8 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os/exec"
-	"strings"
 
 	radiko "github.com/yyoshiki41/go-radiko"
 )
@@ -19,26 +16,17 @@ func main() {
 	dryRun := flag.Bool("dry-run", false, "print playlist URL only")
 	flag.Parse()
 
-	items, err := radiko.GetStreamSmhMultiURL(*stationID)
-	if err != nil {
-		log.Fatalf("failed to get stream info: %v", err)
-	}
-
-	playlistCreateURL, err := selectLivePlaylistCreateURL(items)
-	if err != nil {
-		log.Fatal(err)
-	}
+	ctx := context.Background()
 	client, err := radiko.New("")
 	if err != nil {
 		log.Fatalf("failed to create client: %v", err)
 	}
-	authToken, err := client.AuthorizeToken(context.Background())
-	if err != nil {
+	if _, err := client.AuthorizeToken(ctx); err != nil {
 		log.Fatalf("failed to authorize token: %v", err)
 	}
-	playlistURL, err := resolveLivePlaylistURL(client, playlistCreateURL, authToken)
+	playlistURL, err := client.LivePlaylistM3U8(ctx, *stationID)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("failed to get live playlist: %v", err)
 	}
 	fmt.Printf("playlist: %s\n", playlistURL)
 
@@ -54,49 +42,3 @@ func main() {
 		log.Fatalf("failed to run player command: %v", err)
 	}
 }
-
-func selectLivePlaylistCreateURL(items []radiko.SmhURLItem) (string, error) {
-	// Prefer playlist_create endpoint because it returns a playable m3u URL.
-	for _, item := range items {
-		if strings.Contains(item.PlaylistCreateURL, "/v2/api/playlist_create/") && !item.Areafree {
-			return item.PlaylistCreateURL, nil
-		}
-	}
-	for _, item := range items {
-		if strings.Contains(item.PlaylistCreateURL, "/v2/api/playlist_create/") {
-			return item.PlaylistCreateURL, nil
-		}
-	}
-	return "", fmt.Errorf("no playlist_create URL found in stream info")
-}
-
-func resolveLivePlaylistURL(client *radiko.Client, playlistCreateURL, authToken string) (string, error) {
-	req, err := http.NewRequest(http.MethodGet, playlistCreateURL, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("X-Radiko-App", "pc_html5")
-	req.Header.Set("X-Radiko-App-Version", "0.0.1")
-	req.Header.Set("X-Radiko-User", "test-stream")
-	req.Header.Set("X-Radiko-Device", "pc")
-	req.Header.Set("X-Radiko-AuthToken", authToken)
-	req.Header.Set("pragma", "no-cache")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("playlist_create failed: status=%d", resp.StatusCode)
-	}
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-	playlistURL := strings.TrimSpace(string(b))
-	if playlistURL == "" {
-		return "", fmt.Errorf("empty playlist URL from playlist_create")
-	}
-	return playlistURL, nil
-}
@@ -4,15 +4,14 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"io"
 	"log"
+	"net"
 	"net/http"
-	"os"
 	"os/exec"
-	"strings"
 	"time"
 
 	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/hlsproxy"
 )
 
 const timeshiftLayout = "20060102150405"
@@ -44,28 +43,28 @@ func main() {
 		log.Fatalf("failed to resolve program start time: %v", err)
 	}
 
-	playlistURL, err := client.TimeshiftPlaylistM3U8(ctx, *stationID, programStart)
-	if err != nil {
-		log.Fatalf("failed to get timeshift playlist: %v", err)
-	}
-	fmt.Printf("playlist: %s\n", playlistURL)
-
+	proxy := hlsproxy.New(radiko.NewTokenManager(client))
 	if *dryRun {
+		playlistURL, err := client.TimeshiftPlaylistM3U8(ctx, *stationID, programStart)
+		if err != nil {
+			log.Fatalf("failed to get timeshift playlist: %v", err)
+		}
+		fmt.Printf("playlist: %s\n", playlistURL)
 		return
 	}
 
-	playerArgs, cleanup, err := buildPlayerArgs(*playerCmd, playlistURL)
+	// Players can not send the auth_token, so the playlist is served by a local proxy.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
 	if err != nil {
-		log.Fatalf("failed to prepare player input: %v", err)
-	}
-	if cleanup != nil {
-		defer cleanup()
+		log.Fatalf("failed to listen: %v", err)
 	}
+	go http.Serve(ln, proxy)
 
-	cmd := exec.Command(*playerCmd, playerArgs...)
+	playlistURL := "http://" + ln.Addr().String() + proxy.TimeshiftPath(*stationID, programStart.Format(timeshiftLayout))
+	cmd := exec.Command(*playerCmd, playlistURL)
 	cmd.Stdout = log.Writer()
 	cmd.Stderr = log.Writer()
-	log.Printf("start player: %s %s", *playerCmd, strings.Join(playerArgs, " "))
+	log.Printf("start player: %s %s", *playerCmd, playlistURL)
 	if err := cmd.Run(); err != nil {
 		log.Fatalf("failed to run player command: %v", err)
 	}
@@ -120,46 +119,3 @@ func parseRadikoDatetime(v string) (time.Time, error) {
 	}
 	return time.ParseInLocation(timeshiftLayout, v, loc)
 }
-
-func buildPlayerArgs(playerCmd, playlistURL string) ([]string, func(), error) {
-	// ffplay cannot always auto-detect HLS from the "/tf/medialist" URL.
-	if playerCmd == "ffplay" && strings.Contains(playlistURL, "/tf/medialist") {
-		localM3U8, cleanup, err := downloadPlaylist(playlistURL)
-		if err != nil {
-			return nil, nil, err
-		}
-		return []string{"-protocol_whitelist", "file,http,https,tcp,tls", localM3U8}, cleanup, nil
-	}
-	return []string{playlistURL}, nil, nil
-}
-
-func downloadPlaylist(playlistURL string) (string, func(), error) {
-	resp, err := http.Get(playlistURL)
-	if err != nil {
-		return "", nil, err
-	}
-	defer resp.Body.Close()
-
-	b, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", nil, err
-	}
-	if !strings.Contains(string(b), "#EXTM3U") {
-		return "", nil, fmt.Errorf("invalid playlist response")
-	}
-
-	f, err := os.CreateTemp("", "radiko-timeshift-*.m3u8")
-	if err != nil {
-		return "", nil, err
-	}
-	if _, err := f.Write(b); err != nil {
-		f.Close()
-		os.Remove(f.Name())
-		return "", nil, err
-	}
-	if err := f.Close(); err != nil {
-		os.Remove(f.Name())
-		return "", nil, err
-	}
-	return f.Name(), func() { _ = os.Remove(f.Name()) }, nil
-}
@@ -0,0 +1,273 @@
+// Package feed generates podcast feeds (RSS 2.0 with iTunes extensions)
+// from recorded radiko programs.
+package feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/htmltext"
+)
+
+const (
+	itunesNS = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+	// mediaPath is the path prefix of enclosure URLs.
+	mediaPath = "/media/"
+)
+
+// Channel represents the meta-info of a podcast.
+type Channel struct {
+	Title       string
+	Link        string
+	Description string
+	Language    string
+	Author      string
+	ImageURL    string
+}
+
+// Episode represents a recorded program.
+type Episode struct {
+	// Path is the file path of the recording relative to the feed directory.
+	Path    string
+	Station radiko.Station
+	Prog    radiko.Prog
+}
+
+// GUID returns a unique identifier derived from the station ID and the start time.
+func (e Episode) GUID() string {
+	return e.Station.ID + "-" + e.Prog.Ft
+}
+
+// Feed is a podcast feed over a directory of recordings.
+type Feed struct {
+	Channel Channel
+	// BaseURL is prepended to enclosure URLs.
+	BaseURL string
+
+	dir string
+
+	mu       sync.RWMutex
+	episodes []Episode
+}
+
+// New returns a new Feed for the recordings in dir.
+func New(dir, baseURL string, channel Channel) *Feed {
+	return &Feed{
+		Channel: channel,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		dir:     dir,
+	}
+}
+
+// Add adds a recording located at path (relative to the feed directory).
+// An episode which has the same GUID is replaced.
+func (f *Feed) Add(path string, station radiko.Station, prog radiko.Prog) {
+	e := Episode{Path: filepath.ToSlash(path), Station: station, Prog: prog}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.episodes {
+		if f.episodes[i].GUID() == e.GUID() {
+			f.episodes[i] = e
+			return
+		}
+	}
+	f.episodes = append(f.episodes, e)
+}
+
+// episode returns the registered episode of the recording at path.
+func (f *Feed) episode(path string) (Episode, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, e := range f.episodes {
+		if e.Path == path {
+			return e, true
+		}
+	}
+	return Episode{}, false
+}
+
+// Episodes returns the registered episodes, newest first.
+func (f *Feed) Episodes() []Episode {
+	f.mu.RLock()
+	episodes := make([]Episode, len(f.episodes))
+	copy(episodes, f.episodes)
+	f.mu.RUnlock()
+
+	sort.SliceStable(episodes, func(i, j int) bool {
+		return episodes[i].Prog.Ft > episodes[j].Prog.Ft
+	})
+	return episodes
+}
+
+// WriteTo writes the RSS document to w.
+// Episodes whose recording does not exist or whose Prog has invalid times are omitted.
+func (f *Feed) WriteTo(w io.Writer) (int64, error) {
+	doc, err := f.rss(f.BaseURL)
+	if err != nil {
+		return 0, err
+	}
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, v interface{}) (int64, error) {
+	b, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := w.Write(b)
+	return int64(n + m), err
+}
+
+func (f *Feed) rss(baseURL string) (*rss, error) {
+	ch := rssChannel{
+		Title:        f.Channel.Title,
+		Link:         f.Channel.Link,
+		Description:  f.Channel.Description,
+		Language:     f.Channel.Language,
+		ItunesAuthor: f.Channel.Author,
+	}
+	if f.Channel.ImageURL != "" {
+		ch.ItunesImage = &itunesImage{Href: f.Channel.ImageURL}
+	}
+
+	for _, e := range f.Episodes() {
+		fi, err := os.Stat(filepath.Join(f.dir, filepath.FromSlash(e.Path)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := newItem(baseURL, e, fi.Size())
+		if err != nil {
+			// An episode with a broken Prog must not break the whole feed.
+			continue
+		}
+		ch.Items = append(ch.Items, item)
+	}
+
+	return &rss{
+		Version:  "2.0",
+		ItunesNS: itunesNS,
+		Channel:  ch,
+	}, nil
+}
+
+func newItem(baseURL string, e Episode, length int64) (rssItem, error) {
+	start, err := e.Prog.StartTime()
+	if err != nil {
+		return rssItem{}, err
+	}
+	dur, err := e.Prog.Duration()
+	if err != nil {
+		return rssItem{}, err
+	}
+
+	// Podcast apps render the description as HTML.
+	desc := htmltext.Sanitize(e.Prog.Desc)
+	if desc == "" {
+		desc = htmltext.Sanitize(e.Prog.Info)
+	}
+
+	return rssItem{
+		Title:       e.Prog.Title,
+		Link:        e.Prog.URL,
+		Description: desc,
+		PubDate:     start.Format(time.RFC1123Z),
+		GUID:        rssGUID{Value: e.GUID()},
+		Enclosure: rssEnclosure{
+			URL:    baseURL + mediaPath + (&url.URL{Path: e.Path}).EscapedPath(),
+			Length: length,
+			Type:   mimeType(e.Path),
+		},
+		ItunesAuthor:   e.Prog.Pfm,
+		ItunesSubtitle: e.Prog.SubTitle,
+		ItunesDuration: formatDuration(dur),
+	}, nil
+}
+
+var mimeTypes = map[string]string{
+	".aac": "audio/aac",
+	".m4a": "audio/mp4",
+	".mp3": "audio/mpeg",
+	".ogg": "audio/ogg",
+}
+
+func mimeType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if t, ok := mimeTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// formatDuration returns d formatted in HH:MM:SS for itunes:duration.
+func formatDuration(d time.Duration) string {
+	sec := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", sec/3600, sec/60%60, sec%60)
+}
+
+type rss struct {
+	XMLName  xml.Name   `xml:"rss"`
+	Version  string     `xml:"version,attr"`
+	ItunesNS string     `xml:"xmlns:itunes,attr"`
+	Channel  rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title        string       `xml:"title"`
+	Link         string       `xml:"link"`
+	Description  string       `xml:"description"`
+	Language     string       `xml:"language,omitempty"`
+	ItunesAuthor string       `xml:"itunes:author,omitempty"`
+	ItunesImage  *itunesImage `xml:"itunes:image"`
+	Items        []rssItem    `xml:"item"`
+}
+
+type itunesImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	Title          string       `xml:"title"`
+	Link           string       `xml:"link,omitempty"`
+	Description    string       `xml:"description,omitempty"`
+	PubDate        string       `xml:"pubDate"`
+	GUID           rssGUID      `xml:"guid"`
+	Enclosure      rssEnclosure `xml:"enclosure"`
+	ItunesAuthor   string       `xml:"itunes:author,omitempty"`
+	ItunesSubtitle string       `xml:"itunes:subtitle,omitempty"`
+	ItunesDuration string       `xml:"itunes:duration"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
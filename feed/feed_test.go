@@ -0,0 +1,85 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+var testStation = radiko.Station{ID: "LFR", Name: "ニッポン放送"}
+
+func createTestFeed(t *testing.T) (*Feed, func()) {
+	dir, err := ioutil.TempDir("", "test-go-radiko-feed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "LFR"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "LFR", "ann 1.aac"), make([]byte, 128), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := New(dir, "http://localhost:8080/", Channel{Title: "radiko"})
+	f.Add("LFR/ann 1.aac", testStation, radiko.Prog{
+		Ft: "20161113010000", To: "20161113030000", Dur: "7200", Title: "ANN",
+	})
+	f.Add("LFR/missing.aac", testStation, radiko.Prog{
+		Ft: "20161112230000", To: "20161112233000", Dur: "1800", Title: "missing",
+	})
+	return f, func() { os.RemoveAll(dir) }
+}
+
+func TestFeed_WriteTo(t *testing.T) {
+	f, cleanup := createTestFeed(t)
+	defer cleanup()
+
+	var b bytes.Buffer
+	if _, err := f.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc rss
+	if err := xml.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Channel.Items) != 1 {
+		t.Fatalf("expected 1 item, but %d", len(doc.Channel.Items))
+	}
+	item := doc.Channel.Items[0]
+	if expected := "LFR-20161113010000"; item.GUID.Value != expected {
+		t.Errorf("expected %s, but %s", expected, item.GUID.Value)
+	}
+	enc := item.Enclosure
+	if expected := "http://localhost:8080/media/LFR/ann%201.aac"; enc.URL != expected {
+		t.Errorf("expected %s, but %s", expected, enc.URL)
+	}
+	if enc.Length != 128 || enc.Type != "audio/aac" {
+		t.Errorf("unexpected enclosure: %v", enc)
+	}
+}
+
+func TestFeed_Add_Replace(t *testing.T) {
+	f := New("", "", Channel{})
+	prog := radiko.Prog{Ft: "20161113010000"}
+	f.Add("a.aac", testStation, prog)
+	f.Add("b.aac", testStation, prog)
+
+	episodes := f.Episodes()
+	if len(episodes) != 1 || episodes[0].Path != "b.aac" {
+		t.Errorf("unexpected episodes: %v", episodes)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	d := 2*time.Hour + 3*time.Minute + 4*time.Second
+	if expected, actual := "02:03:04", formatDuration(d); expected != actual {
+		t.Errorf("expected %s, but %s", expected, actual)
+	}
+}
@@ -0,0 +1,69 @@
+package feed
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Handler returns an http.Handler which serves the feed at "/" (or "/feed.xml")
+// and the recordings of the episodes under "/media/".
+// Other files in the directory are not served, and directories are not listed.
+// If f.BaseURL is empty, enclosure URLs are derived from the request.
+func Handler(f *Feed) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(mediaPath, func(w http.ResponseWriter, r *http.Request) {
+		serveMedia(w, r, f, strings.TrimPrefix(r.URL.Path, mediaPath))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Path != "/feed.xml" {
+			http.NotFound(w, r)
+			return
+		}
+
+		baseURL := f.BaseURL
+		if baseURL == "" {
+			baseURL = requestBaseURL(r)
+		}
+		doc, err := f.rss(baseURL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		writeXML(w, doc)
+	})
+	return mux
+}
+
+// serveMedia serves the recording of the episode at path.
+func serveMedia(w http.ResponseWriter, r *http.Request, f *Feed, path string) {
+	e, ok := f.episode(path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	file, err := os.Open(filepath.Join(f.dir, filepath.FromSlash(e.Path)))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	fi, err := file.Stat()
+	if err != nil || fi.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", mimeType(e.Path))
+	http.ServeContent(w, r, fi.Name(), fi.ModTime(), file)
+}
+
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,83 @@
+package feed
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+func TestHandler(t *testing.T) {
+	f, cleanup := createTestFeed(t)
+	defer cleanup()
+	f.BaseURL = ""
+
+	ts := httptest.NewServer(Handler(f))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), ts.URL+"/media/LFR/ann%201.aac") {
+		t.Errorf("enclosure url is not derived from the request: %s", b)
+	}
+
+	resp, err = http.Get(ts.URL + "/media/LFR/ann%201.aac")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != 128 {
+		t.Errorf("unexpected response: %d %d", resp.StatusCode, resp.ContentLength)
+	}
+
+	// Only the recordings of the episodes are served, without listings.
+	if err := ioutil.WriteFile(filepath.Join(f.dir, "LFR", "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/unknown", "/media/", "/media/LFR/", "/media/LFR/secret.txt", "/media/LFR/missing.aac"} {
+		resp, err = http.Get(ts.URL + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected 404, but %d", p, resp.StatusCode)
+		}
+	}
+}
+
+func TestHandler_InvalidEpisode(t *testing.T) {
+	f, cleanup := createTestFeed(t)
+	defer cleanup()
+	f.Add("LFR/ann 1.aac", radiko.Station{ID: "TBS"}, radiko.Prog{Ft: "invalid", Title: "broken"})
+
+	ts := httptest.NewServer(Handler(f))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, but %d", resp.StatusCode)
+	}
+	if strings.Contains(string(b), "broken") || !strings.Contains(string(b), "LFR-20161113010000") {
+		t.Errorf("Should skip only the invalid episode: %s", b)
+	}
+}
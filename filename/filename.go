@@ -0,0 +1,303 @@
+// Package filename expands templates of file names with the meta-info of radiko programs,
+// e.g. "{station}/{date}_{title}.aac", into names which are safe on Linux, macOS and Windows.
+package filename
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/internal/util"
+)
+
+const (
+	// DefaultMaxBytes is the default maximum length of a path element in bytes.
+	// It leaves room for temporary suffixes under the limit of 255 bytes of most filesystems.
+	DefaultMaxBytes = 200
+
+	// maxExtBytes is the maximum length of an extension kept by truncation.
+	maxExtBytes = 16
+
+	// reserved is the characters which are not allowed in file names on Windows.
+	reserved = `<>:"/\|?*`
+)
+
+// layouts is the default layouts of the placeholders of time.
+var layouts = map[string]string{
+	"date": "20060102",
+	"time": "150405",
+}
+
+// windowsNames is the device names reserved on Windows, regardless of the extension.
+var windowsNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// Data is the meta-info expanded in a template.
+type Data struct {
+	Station radiko.Station
+	Prog    radiko.Prog
+	// Time is the time of {date} and {time}, e.g. when a recording starts.
+	// Defaults to the start time of Prog.
+	Time time.Time
+}
+
+// Template is a parsed template of file names.
+//
+// The placeholders are:
+//
+//	{station}       ID of the station
+//	{station_name}  name of the station
+//	{title}         title of the program, or "untitled"
+//	{sub_title}     sub title of the program
+//	{pfm}           performers of the program
+//	{ft}, {to}      start and end time of the program (YYYYMMDDhhmmss)
+//	{date}          Time in JST (YYYYMMDD)
+//	{time}          Time in JST (hhmmss)
+//
+// {date} and {time} take a layout of the time package after a colon, e.g. {date:2006-01-02}.
+//
+// Slashes in the template separate directories, and the other text is kept as it is.
+// The expanded values are sanitized (see Sanitize), so that they do not add directories,
+// and each path element with placeholders is truncated to MaxBytes keeping its extension.
+type Template struct {
+	// MaxBytes is the maximum length of a path element in bytes. Zero means DefaultMaxBytes.
+	MaxBytes int
+
+	text  string
+	parts []part
+}
+
+// part is either a literal text or a placeholder.
+type part struct {
+	literal string
+	name    string
+	layout  string
+}
+
+// Parse parses the template.
+func Parse(text string) (*Template, error) {
+	t := &Template{text: text}
+	for s := text; s != ""; {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			t.parts = append(t.parts, part{literal: s})
+			break
+		}
+		if i > 0 {
+			t.parts = append(t.parts, part{literal: s[:i]})
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			return nil, fmt.Errorf("filename: unclosed placeholder in %q", text)
+		}
+		p, err := parsePlaceholder(s[i+1 : i+j])
+		if err != nil {
+			return nil, err
+		}
+		t.parts = append(t.parts, p)
+		s = s[i+j+1:]
+	}
+	return t, nil
+}
+
+// MustParse is like Parse but panics if the template cannot be parsed.
+func MustParse(text string) *Template {
+	t, err := Parse(text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func parsePlaceholder(s string) (part, error) {
+	name, layout := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		name, layout = s[:i], s[i+1:]
+	}
+
+	switch name {
+	case "station", "station_name", "title", "sub_title", "pfm", "ft", "to":
+		if layout != "" {
+			return part{}, fmt.Errorf("filename: {%s} does not take a layout", name)
+		}
+	case "date", "time":
+		if layout == "" {
+			layout = layouts[name]
+		}
+	default:
+		return part{}, fmt.Errorf("filename: unknown placeholder {%s}", s)
+	}
+	return part{name: name, layout: layout}, nil
+}
+
+// String returns the text of the template.
+func (t *Template) String() string {
+	return t.text
+}
+
+// Execute returns the file name of the data, whose separators are slashes.
+func (t *Template) Execute(d Data) string {
+	if d.Time.IsZero() {
+		d.Time, _ = d.Prog.StartTime()
+	}
+
+	var elems []string
+	var elem strings.Builder
+	expanded := false
+	flush := func() {
+		s := elem.String()
+		if expanded {
+			s = t.element(s)
+		}
+		elems = append(elems, s)
+		elem.Reset()
+		expanded = false
+	}
+	for _, p := range t.parts {
+		if p.name != "" {
+			elem.WriteString(sanitize(value(p, d)))
+			expanded = true
+			continue
+		}
+		l := strings.Split(p.literal, "/")
+		for i, s := range l {
+			elem.WriteString(s)
+			if i < len(l)-1 {
+				flush()
+			}
+		}
+	}
+	flush()
+	return strings.Join(elems, "/")
+}
+
+func value(p part, d Data) string {
+	switch p.name {
+	case "station":
+		return d.Station.ID
+	case "station_name":
+		return d.Station.Name
+	case "title":
+		if d.Prog.Title == "" {
+			return "untitled"
+		}
+		return d.Prog.Title
+	case "sub_title":
+		return d.Prog.SubTitle
+	case "pfm":
+		return d.Prog.Pfm
+	case "ft":
+		return d.Prog.Ft
+	case "to":
+		return d.Prog.To
+	case "date", "time":
+		if d.Time.IsZero() {
+			return ""
+		}
+		return d.Time.In(util.Location()).Format(p.layout)
+	}
+	return ""
+}
+
+// element makes the path element with placeholders safe,
+// truncating it to MaxBytes with its extension.
+func (t *Template) element(s string) string {
+	max := t.MaxBytes
+	if max <= 0 {
+		max = DefaultMaxBytes
+	}
+	if len(s) > max {
+		ext := path.Ext(s)
+		if len(ext) > maxExtBytes || len(ext) >= max {
+			ext = ""
+		}
+		s = trimRight(Truncate(strings.TrimSuffix(s, ext), max-len(ext))) + ext
+	}
+	return safeName(trimRight(s))
+}
+
+// Sanitize returns s which is safe as a file name on Linux, macOS and Windows.
+// Full-width ASCII characters and spaces are converted to half-width,
+// the characters reserved on Windows (including slashes) are replaced with "_",
+// control characters and runs of spaces are replaced with a space,
+// leading and trailing spaces and dots are removed,
+// and the device names of Windows (e.g. "CON") are prefixed with "_".
+// An empty result is "_".
+func Sanitize(s string) string {
+	return safeName(sanitize(s))
+}
+
+// sanitize is Sanitize for a part of a file name, which may be empty.
+func sanitize(s string) string {
+	var b strings.Builder
+	space := false
+	for i, r := range s {
+		switch {
+		case r == utf8.RuneError && !strings.HasPrefix(s[i:], "\uFFFD"):
+			// Invalid UTF-8.
+			r = '_'
+		case r == '　':
+			r = ' '
+		case '！' <= r && r <= '～':
+			// Full-width ASCII.
+			r -= 0xFEE0
+		}
+		if strings.ContainsRune(reserved, r) {
+			r = '_'
+		}
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			space = true
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return strings.Trim(b.String(), " .")
+}
+
+// Truncate returns the longest prefix of s within n bytes, which does not split a UTF-8 character.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	if n <= 0 {
+		return ""
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// trimRight removes trailing spaces and dots, which are not allowed on Windows.
+func trimRight(s string) string {
+	return strings.TrimRight(s, " .")
+}
+
+// safeName returns "_" for empty names and names of "." and "..",
+// and prefixes the device names of Windows with "_".
+func safeName(s string) string {
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	base := s
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		base = s[:i]
+	}
+	if windowsNames[strings.ToUpper(base)] {
+		return "_" + s
+	}
+	return s
+}
@@ -0,0 +1,124 @@
+package filename
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+func testData() Data {
+	return Data{
+		Station: radiko.Station{ID: "LFR", Name: "ニッポン放送"},
+		Prog: radiko.Prog{
+			Ft:    "20161112230000",
+			To:    "20161112233000",
+			Title: "中居正広のＳｏｍｅ　ｇｉｒｌ’ ＳＭＡＰ",
+			Pfm:   "中居正広（ＳＭＡＰ）",
+		},
+	}
+}
+
+func TestTemplate_Execute(t *testing.T) {
+	d := testData()
+	recorded := d
+	recorded.Time = time.Date(2016, 11, 12, 14, 5, 6, 0, time.UTC)
+
+	cases := []struct {
+		tmpl     string
+		d        Data
+		expected string
+	}{
+		{"{station}/{date}_{title}.aac", d, "LFR/20161112_中居正広のSome girl’ SMAP.aac"},
+		{"{station_name}_{ft}-{to}.aac", d, "ニッポン放送_20161112230000-20161112233000.aac"},
+		{"{date:2006/01/02}/{time}_{pfm}.aac", d, "2016_11_12/230000_中居正広(SMAP).aac"},
+		// Time is converted to JST.
+		{"/var/radiko/{station}_{date}{time}.aac", recorded, "/var/radiko/LFR_20161112230506.aac"},
+		{"{title}.aac", Data{}, "untitled.aac"},
+		{"{sub_title}/{station}", d, "_/LFR"},
+		{"out.aac", d, "out.aac"},
+	}
+	for _, c := range cases {
+		tmpl, err := Parse(c.tmpl)
+		if err != nil {
+			t.Fatalf("Failed to parse %s: %s", c.tmpl, err)
+		}
+		if s := tmpl.Execute(c.d); s != c.expected {
+			t.Errorf("expected %s, but %s", c.expected, s)
+		}
+	}
+}
+
+func TestTemplate_Execute_Truncate(t *testing.T) {
+	d := testData()
+	d.Prog.Title = strings.Repeat("オールナイトニッポン", 20)
+
+	tmpl := MustParse("{station}/{title}.aac")
+	s := tmpl.Execute(d)
+	elems := strings.Split(s, "/")
+	if len(elems[1]) > DefaultMaxBytes || !utf8.ValidString(s) || !strings.HasSuffix(s, ".aac") {
+		t.Errorf("unexpected name: %s", s)
+	}
+
+	tmpl.MaxBytes = 10
+	if s := tmpl.Execute(d); s != "LFR/オー.aac" {
+		t.Errorf("expected LFR/オー.aac, but %s", s)
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	for _, s := range []string{"{station", "{unknown}.aac", "{title:2006}.aac"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("Should detect an error: %s", s)
+		}
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected string
+	}{
+		{"AC/DC\\特集", "AC_DC_特集"},
+		{`a<b>c:d"e|f?g*h`, "a_b_c_d_e_f_g_h"},
+		{"ＡＢＣ／１２３　！", "ABC_123 !"},
+		{" ..tab\tand\nnewline.. ", "tab and newline"},
+		{"con", "_con"},
+		{"LPT1.aac", "_LPT1.aac"},
+		{"...", "_"},
+		{"bad\xffutf8", "bad_utf8"},
+	}
+	for _, c := range cases {
+		if s := Sanitize(c.s); s != c.expected {
+			t.Errorf("expected %q, but %q", c.expected, s)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		s        string
+		n        int
+		expected string
+	}{
+		{"radiko", 10, "radiko"},
+		{"radiko", 3, "rad"},
+		{"ラジコ", 7, "ラジ"},
+		{"ラジコ", 2, ""},
+		{"ラジコ", 0, ""},
+	}
+	for _, c := range cases {
+		if s := Truncate(c.s, c.n); s != c.expected {
+			t.Errorf("expected %q, but %q", c.expected, s)
+		}
+	}
+}
+
+func TestTemplate_Execute_ProgStartTime(t *testing.T) {
+	// The start time of the program is used without Time.
+	if s := MustParse("{date}{time}").Execute(testData()); s != "20161112230000" {
+		t.Errorf("expected 20161112230000, but %s", s)
+	}
+}
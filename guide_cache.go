@@ -0,0 +1,297 @@
+package radiko
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGuideTTL is the lifetime of a cached program guide whose response has no <ttl>.
+	DefaultGuideTTL = 5 * time.Minute
+	// DefaultGuideMaxStale is how long an expired program guide is used
+	// while radiko can not be reached.
+	DefaultGuideMaxStale = time.Hour
+)
+
+// GuideStore persists cached program guides.
+type GuideStore interface {
+	// Load returns the value saved with the key.
+	Load(key string) ([]byte, error)
+	// Save saves the value with the key.
+	Save(key string, value []byte) error
+}
+
+// DiskGuideStore is a GuideStore which saves values as files in Dir.
+type DiskGuideStore struct {
+	Dir string
+}
+
+// Load implements the GuideStore interface.
+func (s *DiskGuideStore) Load(key string) ([]byte, error) {
+	return ioutil.ReadFile(s.path(key))
+}
+
+// Save implements the GuideStore interface.
+// The file is replaced atomically.
+func (s *DiskGuideStore) Save(key string, value []byte) error {
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(s.Dir, ".guide-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(value); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), s.path(key))
+}
+
+func (s *DiskGuideStore) path(key string) string {
+	return filepath.Join(s.Dir, strings.Replace(key, "/", "_", -1)+".json")
+}
+
+// GuideCache caches the program guides of GetStations (by area and broadcast date)
+// and GetWeeklyPrograms (by station and week).
+// It is safe for concurrent use, and every call gets its own copy of a guide.
+// Guides expired for more than MaxStale are evicted from memory.
+// The zero value is a cache in memory with the default settings.
+type GuideCache struct {
+	// Store persists the guides if set, so they survive restarts.
+	Store GuideStore
+	// DefaultTTL is used when a response has no <ttl>.
+	// Zero means DefaultGuideTTL.
+	DefaultTTL time.Duration
+	// MaxStale is how long an expired guide is used while radiko can not be reached.
+	// Zero means DefaultGuideMaxStale, and a negative value disables it.
+	MaxStale time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*guideEntry
+}
+
+// NewGuideCache returns a new GuideCache which persists the guides in store.
+// store may be nil to cache in memory only.
+func NewGuideCache(store GuideStore) *GuideCache {
+	return &GuideCache{
+		Store:      store,
+		DefaultTTL: DefaultGuideTTL,
+		MaxStale:   DefaultGuideMaxStale,
+	}
+}
+
+// guideEntry is a cached response.
+// Only the exported fields are persisted.
+type guideEntry struct {
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	TTL          int64     `json:"ttl"`
+	Expires      time.Time `json:"expires"`
+
+	data *stationsData
+}
+
+// Purge removes all guides cached in memory.
+func (gc *GuideCache) Purge() {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.entries = nil
+}
+
+// get returns the guide of the request.
+// An expired guide is revalidated with a conditional request.
+func (gc *GuideCache) get(c *Client, req *http.Request, key string) (*stationsData, error) {
+	now := time.Now()
+	e := gc.load(key)
+	if e != nil && now.Before(e.Expires) {
+		return e.data.clone(), nil
+	}
+
+	if e != nil {
+		if e.ETag != "" {
+			req.Header.Set("If-None-Match", e.ETag)
+		}
+		if e.LastModified != "" {
+			req.Header.Set("If-Modified-Since", e.LastModified)
+		}
+	}
+	sent := time.Now()
+	resp, err := c.Do(req)
+	if err != nil {
+		return gc.stale(e, now, err)
+	}
+	defer resp.Body.Close()
+	received := time.Now()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && e != nil:
+		renewed := *e
+		renewed.Expires = now.Add(time.Duration(e.TTL))
+		gc.save(key, &renewed)
+		return renewed.data.clone(), nil
+	case resp.StatusCode >= 500:
+		return gc.stale(e, now, guideStatusError(resp))
+	case resp.StatusCode != http.StatusOK:
+		return nil, guideStatusError(resp)
+	}
+
+	// The body is kept only when it is persisted.
+	var buf bytes.Buffer
+	var body io.Reader = resp.Body
+	if gc.Store != nil {
+		body = io.TeeReader(resp.Body, &buf)
+	}
+	var d stationsData
+	if err = decodeStationsData(body, &d); err != nil {
+		return nil, err
+	}
+	c.updateClockOffset(&d, resp.Header, sent, received)
+
+	ttl := gc.defaultTTL()
+	if d.TTL > 0 {
+		ttl = time.Duration(d.TTL) * time.Second
+	}
+	gc.save(key, &guideEntry{
+		Body:         buf.Bytes(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		TTL:          int64(ttl),
+		Expires:      now.Add(ttl),
+		data:         &d,
+	})
+	return d.clone(), nil
+}
+
+// stale returns the expired guide if it is within MaxStale, or err otherwise.
+func (gc *GuideCache) stale(e *guideEntry, now time.Time, err error) (*stationsData, error) {
+	if e != nil && now.Before(e.Expires.Add(gc.maxStale())) {
+		return e.data.clone(), nil
+	}
+	return nil, err
+}
+
+// load returns the entry in memory, or in the store.
+func (gc *GuideCache) load(key string) *guideEntry {
+	gc.mu.Lock()
+	e, ok := gc.entries[key]
+	gc.mu.Unlock()
+	if ok || gc.Store == nil {
+		return e
+	}
+
+	b, err := gc.Store.Load(key)
+	if err != nil {
+		return nil
+	}
+	e = &guideEntry{}
+	if err := json.Unmarshal(b, e); err != nil {
+		return nil
+	}
+	var d stationsData
+	if err := decodeStationsData(bytes.NewReader(e.Body), &d); err != nil {
+		return nil
+	}
+	e.data = &d
+
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.put(key, e)
+	return e
+}
+
+// save stores the entry in memory, and in the store.
+// Failures of the store are ignored because the entry is still cached in memory.
+// The entries which can no longer be used are evicted from memory.
+func (gc *GuideCache) save(key string, e *guideEntry) {
+	now := time.Now()
+	gc.mu.Lock()
+	for k, old := range gc.entries {
+		if !now.Before(old.Expires.Add(gc.maxStale())) {
+			delete(gc.entries, k)
+		}
+	}
+	gc.put(key, e)
+	gc.mu.Unlock()
+
+	if gc.Store == nil {
+		return
+	}
+	if b, err := json.Marshal(e); err == nil {
+		gc.Store.Save(key, b)
+	}
+}
+
+// clone returns a deep copy of the guide, so that a cached guide is not modified by callers.
+func (d *stationsData) clone() *stationsData {
+	c := *d
+	c.XMLStations.Stations = make(Stations, len(d.XMLStations.Stations))
+	for i, s := range d.XMLStations.Stations {
+		s.Scd.Progs.Progs = append([]Prog(nil), s.Scd.Progs.Progs...)
+		s.Progs.Progs = append([]Prog(nil), s.Progs.Progs...)
+		c.XMLStations.Stations[i] = s
+	}
+	return &c
+}
+
+// put stores the entry in memory. gc.mu must be held.
+func (gc *GuideCache) put(key string, e *guideEntry) {
+	if gc.entries == nil {
+		gc.entries = map[string]*guideEntry{}
+	}
+	gc.entries[key] = e
+}
+
+func (gc *GuideCache) defaultTTL() time.Duration {
+	if gc.DefaultTTL > 0 {
+		return gc.DefaultTTL
+	}
+	return DefaultGuideTTL
+}
+
+func (gc *GuideCache) maxStale() time.Duration {
+	switch {
+	case gc.MaxStale > 0:
+		return gc.MaxStale
+	case gc.MaxStale < 0:
+		return 0
+	}
+	return DefaultGuideMaxStale
+}
+
+// GuideCache returns the cache of program guides, nil if it is disabled.
+func (c *Client) GuideCache() *GuideCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.guideCache
+}
+
+// SetGuideCache enables the cache of program guides. nil disables it.
+func (c *Client) SetGuideCache(gc *GuideCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.guideCache = gc
+}
+
+// getGuide returns the program guide of the request, using the cache if enabled.
+func (c *Client) getGuide(req *http.Request, key string) (*stationsData, error) {
+	gc := c.GuideCache()
+	if gc == nil {
+		return c.getStationsData(req)
+	}
+	return gc.get(c, req, key)
+}
@@ -0,0 +1,205 @@
+package radiko
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newGuideTestServer(t *testing.T) (*httptest.Server, *int32) {
+	var n int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&n, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeFile(w, r, filepath.Join(testdataDir, "stations.xml"))
+	}))
+	return ts, &n
+}
+
+func expireGuides(gc *GuideCache) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	for _, e := range gc.entries {
+		e.Expires = time.Now().Add(-time.Second)
+	}
+}
+
+func TestGuideCache(t *testing.T) {
+	ts, n := newGuideTestServer(t)
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	gc := NewGuideCache(nil)
+	client.SetGuideCache(gc)
+	ctx := context.Background()
+	date := time.Date(2016, 11, 12, 23, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		stations, err := client.GetStations(ctx, date)
+		if err != nil {
+			t.Fatalf("Failed to get stations: %s", err)
+		}
+		if len(stations) != 2 {
+			t.Errorf("expected 2 stations, but %d", len(stations))
+		}
+	}
+	if atomic.LoadInt32(n) != 1 {
+		t.Errorf("expected 1 request, but %d", atomic.LoadInt32(n))
+	}
+
+	// Another date and the weekly programs have their own entries.
+	if _, err := client.GetStations(ctx, date.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("Failed to get stations: %s", err)
+	}
+	if _, err := client.GetWeeklyPrograms(ctx, "LFR"); err != nil {
+		t.Fatalf("Failed to get weekly programs: %s", err)
+	}
+	if atomic.LoadInt32(n) != 3 {
+		t.Errorf("expected 3 requests, but %d", atomic.LoadInt32(n))
+	}
+
+	// Expired entries are revalidated.
+	expireGuides(gc)
+	stations, err := client.GetStations(ctx, date)
+	if err != nil {
+		t.Fatalf("Failed to revalidate stations: %s", err)
+	}
+	if len(stations) != 2 || atomic.LoadInt32(n) != 4 {
+		t.Errorf("unexpected revalidation: %d stations, %d requests", len(stations), atomic.LoadInt32(n))
+	}
+	if _, err := client.GetStations(ctx, date); err != nil || atomic.LoadInt32(n) != 4 {
+		t.Errorf("revalidated entry should be fresh: %v, %d requests", err, atomic.LoadInt32(n))
+	}
+
+	// Expired entries are used while radiko can not be reached.
+	ts.Close()
+	expireGuides(gc)
+	if _, err := client.GetStations(ctx, date); err != nil {
+		t.Errorf("stale entry should be used: %s", err)
+	}
+	gc.MaxStale = -1
+	if _, err := client.GetStations(ctx, date); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestDiskGuideStore(t *testing.T) {
+	ts, n := newGuideTestServer(t)
+	defer ts.Close()
+
+	store := &DiskGuideStore{Dir: filepath.Join(t.TempDir(), "guide")}
+	client := newTestClient(t, ts)
+	client.SetGuideCache(NewGuideCache(store))
+	ctx := context.Background()
+	date := time.Date(2016, 11, 12, 23, 0, 0, 0, time.UTC)
+	if _, err := client.GetStations(ctx, date); err != nil {
+		t.Fatalf("Failed to get stations: %s", err)
+	}
+
+	// A new cache loads the guide from the disk.
+	client.SetGuideCache(NewGuideCache(store))
+	stations, err := client.GetStations(ctx, date)
+	if err != nil {
+		t.Fatalf("Failed to get stations: %s", err)
+	}
+	if len(stations) != 2 || len(stations[1].Programs()) != 2 {
+		t.Errorf("unexpected stations: %+v", stations)
+	}
+	if atomic.LoadInt32(n) != 1 {
+		t.Errorf("expected 1 request, but %d", atomic.LoadInt32(n))
+	}
+
+	if _, err := store.Load("unknown"); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestGuideCache_Copy(t *testing.T) {
+	ts, _ := newGuideTestServer(t)
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	client.SetGuideCache(NewGuideCache(nil))
+	ctx := context.Background()
+	date := time.Date(2016, 11, 12, 23, 0, 0, 0, time.UTC)
+
+	stations, err := client.GetStations(ctx, date)
+	if err != nil {
+		t.Fatalf("Failed to get stations: %s", err)
+	}
+	stations[0].Name = "modified"
+	stations[0].Programs()[0].Title = "modified"
+
+	stations, err = client.GetStations(ctx, date)
+	if err != nil {
+		t.Fatalf("Failed to get stations: %s", err)
+	}
+	if stations[0].Name == "modified" || stations[0].Programs()[0].Title == "modified" {
+		t.Errorf("Should not share the cached guide: %+v", stations[0])
+	}
+}
+
+func TestGuideCache_Evict(t *testing.T) {
+	ts, _ := newGuideTestServer(t)
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	gc := NewGuideCache(nil)
+	client.SetGuideCache(gc)
+	ctx := context.Background()
+	date := time.Date(2016, 11, 12, 23, 0, 0, 0, time.UTC)
+
+	if _, err := client.GetStations(ctx, date); err != nil {
+		t.Fatalf("Failed to get stations: %s", err)
+	}
+	gc.mu.Lock()
+	for _, e := range gc.entries {
+		e.Expires = time.Now().Add(-gc.MaxStale - time.Second)
+	}
+	gc.mu.Unlock()
+
+	if _, err := client.GetWeeklyPrograms(ctx, "LFR"); err != nil {
+		t.Fatalf("Failed to get weekly programs: %s", err)
+	}
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	if len(gc.entries) != 1 {
+		t.Errorf("Should evict the expired guide: %d entries", len(gc.entries))
+	}
+}
+
+func TestGuideCache_ZeroValue(t *testing.T) {
+	ts, n := newGuideTestServer(t)
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	gc := &GuideCache{}
+	client.SetGuideCache(gc)
+	ctx := context.Background()
+	date := time.Date(2016, 11, 12, 23, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetStations(ctx, date); err != nil {
+			t.Fatalf("Failed to get stations: %s", err)
+		}
+	}
+	if atomic.LoadInt32(n) != 1 {
+		t.Errorf("expected 1 request, but %d", atomic.LoadInt32(n))
+	}
+	if gc.defaultTTL() != DefaultGuideTTL || gc.maxStale() != DefaultGuideMaxStale {
+		t.Errorf("unexpected defaults: %s, %s", gc.defaultTTL(), gc.maxStale())
+	}
+
+	gc.Purge()
+	if _, err := client.GetStations(ctx, date); err != nil {
+		t.Fatalf("Failed to get stations: %s", err)
+	}
+}
@@ -0,0 +1,115 @@
+package radiko
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// GuideDecoder decodes a program guide XML incrementally,
+// so that a large guide is not held in memory at once.
+//
+// Use either NextStation or NextProg for a decoder.
+type GuideDecoder struct {
+	// TTL is <ttl> of the guide, which is set once it is decoded.
+	TTL int
+	// Srvtime is <srvtime> of the guide, which is set once it is decoded.
+	Srvtime int64
+
+	d     *xml.Decoder
+	depth int
+	root  bool
+
+	// station is the station being decoded by NextProg.
+	station      *Station
+	stationDepth int
+}
+
+// NewGuideDecoder returns a new GuideDecoder reading from r.
+func NewGuideDecoder(r io.Reader) *GuideDecoder {
+	return &GuideDecoder{d: xml.NewDecoder(r)}
+}
+
+// NextStation returns the next station with its programs.
+// It returns io.EOF at the end of the guide.
+func (g *GuideDecoder) NextStation() (Station, error) {
+	var s Station
+	_, err := g.next(&s)
+	return s, err
+}
+
+// NextProg returns the next program and its station,
+// which has the ID and name but not the programs.
+// It returns io.EOF at the end of the guide.
+func (g *GuideDecoder) NextProg() (Station, Prog, error) {
+	p, err := g.next(nil)
+	if err != nil {
+		return Station{}, Prog{}, err
+	}
+	return *g.station, p, nil
+}
+
+// next decodes the next station into s if s is not nil,
+// or returns the next program otherwise.
+func (g *GuideDecoder) next(s *Station) (Prog, error) {
+	for {
+		tok, err := g.d.Token()
+		if err == io.EOF && !g.root {
+			return Prog{}, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return Prog{}, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			g.depth++
+			name := t.Name.Local
+			switch {
+			case g.depth == 1:
+				if name != "radiko" {
+					return Prog{}, fmt.Errorf("expected element type <radiko> but have <%s>", name)
+				}
+				g.root = true
+			case g.depth == 2 && name == "ttl":
+				err = g.decode(&g.TTL, t)
+			case g.depth == 2 && name == "srvtime":
+				err = g.decode(&g.Srvtime, t)
+			case name == "station" && g.station == nil:
+				if s != nil {
+					return Prog{}, g.decode(s, t)
+				}
+				g.station = &Station{ID: attr(t, "id")}
+				g.stationDepth = g.depth
+			case g.station != nil && name == "name" && g.depth == g.stationDepth+1:
+				err = g.decode(&g.station.Name, t)
+			case g.station != nil && name == "prog":
+				var p Prog
+				return p, g.decode(&p, t)
+			}
+			if err != nil {
+				return Prog{}, err
+			}
+		case xml.EndElement:
+			if g.station != nil && g.depth == g.stationDepth {
+				g.station = nil
+			}
+			g.depth--
+		}
+	}
+}
+
+// decode decodes the element which start is already read.
+func (g *GuideDecoder) decode(v interface{}, start xml.StartElement) error {
+	g.depth--
+	return g.d.DecodeElement(v, &start)
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
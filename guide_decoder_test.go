@@ -0,0 +1,156 @@
+package radiko
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGuideDecoder_NextProg(t *testing.T) {
+	file, err := os.Open(filepath.Join(testdataDir, "stations.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	g := NewGuideDecoder(file)
+	var got []string
+	for {
+		s, p, err := g.NextProg()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to decode: %s", err)
+		}
+		if s.Name == "" || len(s.Programs()) != 0 {
+			t.Errorf("unexpected station: %+v", s)
+		}
+		got = append(got, s.ID+"/"+p.Ft)
+	}
+
+	expected := []string{"TBS/20161112220000", "LFR/20161112230000", "LFR/20161112233000"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, but %v", expected, got)
+	}
+	if g.TTL != 300 || g.Srvtime != 1478960627 {
+		t.Errorf("unexpected ttl and srvtime: %d, %d", g.TTL, g.Srvtime)
+	}
+}
+
+func TestGuideDecoder_NextStation(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join(testdataDir, "stations.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected stationsData
+	if err := xml.Unmarshal(b, &expected); err != nil {
+		t.Fatal(err)
+	}
+
+	var d stationsData
+	if err := decodeStationsData(strings.NewReader(string(b)), &d); err != nil {
+		t.Fatalf("Failed to decode: %s", err)
+	}
+	d.XMLName = expected.XMLName
+	d.XMLStations.XMLName = expected.XMLStations.XMLName
+	if !reflect.DeepEqual(d, expected) {
+		t.Errorf("expected %+v, but %+v", expected, d)
+	}
+}
+
+func TestGuideDecoder_Invalid(t *testing.T) {
+	for _, input := range []string{"", "<html></html>", "<radiko><stations><station>"} {
+		var d stationsData
+		if err := decodeStationsData(strings.NewReader(input), &d); err == nil {
+			t.Errorf("Should detect an error: %q", input)
+		}
+	}
+}
+
+func TestClient_WalkPrograms(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, filepath.Join(testdataDir, "stations.xml"))
+	}))
+	defer ts.Close()
+	client := newTestClient(t, ts)
+	ctx := context.Background()
+	date := time.Date(2016, 11, 12, 23, 0, 0, 0, time.UTC)
+
+	calls := 0
+	err := client.WalkPrograms(ctx, date, func(s Station, p Prog) error {
+		calls++
+		return ErrStopWalk
+	})
+	if err != nil {
+		t.Errorf("ErrStopWalk should not be returned: %s", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, but %d", calls)
+	}
+
+	errTest := errors.New("test")
+	err = client.WalkPrograms(ctx, date, func(s Station, p Prog) error {
+		return errTest
+	})
+	if err != errTest {
+		t.Errorf("expected %v, but %v", errTest, err)
+	}
+
+	// The cached guide is walked in the same way.
+	client.SetGuideCache(NewGuideCache(nil))
+	calls = 0
+	err = client.WalkWeeklyPrograms(ctx, "LFR", func(s Station, p Prog) error {
+		calls++
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Failed to walk: %s", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, but %d", calls)
+	}
+
+	prog, err := client.GetProgramByStartTime(ctx, "LFR", time.Date(2016, 11, 12, 14, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Failed to get the program: %s", err)
+	}
+	if prog.Ft != "20161112233000" {
+		t.Errorf("expected 20161112233000, but %s", prog.Ft)
+	}
+}
+
+func TestClient_WalkPrograms_StatusError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The body is a guide, which must not be walked.
+		b, _ := ioutil.ReadFile(filepath.Join(testdataDir, "stations.xml"))
+		w.WriteHeader(http.StatusNotFound)
+		w.Write(b)
+	}))
+	defer ts.Close()
+	client := newTestClient(t, ts)
+	ctx := context.Background()
+	date := time.Date(2016, 11, 12, 23, 0, 0, 0, time.UTC)
+
+	for _, gc := range []*GuideCache{nil, NewGuideCache(nil)} {
+		client.SetGuideCache(gc)
+		calls := 0
+		err := client.WalkPrograms(ctx, date, func(s Station, p Prog) error {
+			calls++
+			return nil
+		})
+		if err == nil || calls != 0 {
+			t.Errorf("Should detect an error: %v, %d calls", err, calls)
+		}
+	}
+}
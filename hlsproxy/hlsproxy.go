@@ -0,0 +1,292 @@
+// Package hlsproxy serves radiko's HLS streams to local players.
+//
+// Playlists are rewritten so that every uri points back to the proxy with
+// a normalized extension (.m3u8 for playlists), and upstream requests carry
+// the auth_token, which is refreshed transparently when radiko rejects it.
+//
+// Endpoints:
+//
+//	/live/{stationID}/playlist.m3u8           the live stream of the station
+//	/timeshift/{stationID}/{ft}/playlist.m3u8 the timeshift program which starts at ft (YYYYMMDDhhmmss in JST)
+//	/upstream/{encoded url}.{ext}             playlists and segments referenced by the above
+package hlsproxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/internal/hls"
+	"github.com/yyoshiki41/go-radiko/internal/util"
+)
+
+const (
+	playlistName        = "playlist.m3u8"
+	playlistExt         = ".m3u8"
+	playlistContentType = "application/vnd.apple.mpegurl"
+	defaultSegmentExt   = ".aac"
+	upstreamPath        = "/upstream/"
+)
+
+// DefaultAllowedHosts are the upstream domains which may be proxied.
+var DefaultAllowedHosts = []string{"radiko.jp", "smartstream.ne.jp"}
+
+var uriAttr = regexp.MustCompile(`URI="([^"]*)"`)
+
+// Proxy is an http.Handler which proxies radiko's HLS streams.
+type Proxy struct {
+	// BasePath is the path prefix under which the proxy is mounted.
+	BasePath string
+	// AllowedHosts are the upstream domains (including their subdomains) which may be proxied.
+	AllowedHosts []string
+
+	tokens *radiko.TokenManager
+	client *radiko.Client
+}
+
+// New returns a new Proxy which accesses radiko with the managed client.
+func New(tokens *radiko.TokenManager) *Proxy {
+	return &Proxy{
+		AllowedHosts: DefaultAllowedHosts,
+		tokens:       tokens,
+		client:       tokens.Client(),
+	}
+}
+
+// LivePath returns the path of the live playlist of the station.
+func (p *Proxy) LivePath(stationID string) string {
+	return path.Join(p.BasePath, "/live", stationID, playlistName)
+}
+
+// TimeshiftPath returns the path of the timeshift playlist of the program.
+func (p *Proxy) TimeshiftPath(stationID, ft string) string {
+	return path.Join(p.BasePath, "/timeshift", stationID, ft, playlistName)
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rel := strings.TrimPrefix(r.URL.Path, p.BasePath)
+	if strings.HasPrefix(rel, upstreamPath) {
+		p.serveUpstream(w, r, strings.TrimPrefix(rel, upstreamPath))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(rel, "/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "live" && parts[2] == playlistName:
+		p.serveResolved(w, r, func(ctx context.Context) (string, error) {
+			return p.client.LivePlaylistM3U8(ctx, parts[1])
+		})
+	case len(parts) == 4 && parts[0] == "timeshift" && parts[3] == playlistName:
+		start, err := util.ParseDatetime(parts[2])
+		if err != nil {
+			http.Error(w, "invalid ft, use YYYYMMDDhhmmss", http.StatusBadRequest)
+			return
+		}
+		p.serveResolved(w, r, func(ctx context.Context) (string, error) {
+			return p.client.TimeshiftPlaylistM3U8(ctx, parts[1], start)
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveResolved serves the playlist whose url is resolved by fn.
+// fn is retried once with a refreshed auth_token if radiko rejects it.
+func (p *Proxy) serveResolved(w http.ResponseWriter, r *http.Request, fn func(context.Context) (string, error)) {
+	ctx := r.Context()
+	if err := p.tokens.Authorize(ctx); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	token := p.client.AuthToken()
+	uri, err := fn(ctx)
+	if rejected(err) {
+		if err = p.tokens.RefreshRejected(ctx, token); err == nil {
+			uri, err = fn(ctx)
+		}
+	}
+	var terr *radiko.TimefreeError
+	if errors.Is(err, radiko.ErrProgramNotFound) || errors.Is(err, radiko.ErrStreamURLNotFound) || errors.As(err, &terr) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	p.servePlaylist(w, r, uri)
+}
+
+// rejected reports whether err is of a playlist create url which rejected the auth_token.
+func rejected(err error) bool {
+	var serr *radiko.PlaylistStatusError
+	return errors.As(err, &serr) &&
+		(serr.StatusCode == http.StatusUnauthorized || serr.StatusCode == http.StatusForbidden)
+}
+
+func (p *Proxy) serveUpstream(w http.ResponseWriter, r *http.Request, name string) {
+	ext := path.Ext(name)
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimSuffix(name, ext))
+	if err != nil {
+		http.Error(w, "invalid upstream", http.StatusBadRequest)
+		return
+	}
+	uri := string(b)
+	if !p.allowed(uri) {
+		http.Error(w, "upstream is not allowed", http.StatusForbidden)
+		return
+	}
+
+	if ext == playlistExt {
+		p.servePlaylist(w, r, uri)
+		return
+	}
+
+	resp, err := p.get(r.Context(), uri)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	if cl := resp.Header.Get("Content-Length"); cl != "" {
+		w.Header().Set("Content-Length", cl)
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != "HEAD" {
+		io.Copy(w, resp.Body)
+	}
+}
+
+func (p *Proxy) servePlaylist(w http.ResponseWriter, r *http.Request, uri string) {
+	resp, err := p.get(r.Context(), uri)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	playlist, err := p.rewrite(body, uri)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", playlistContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	if r.Method != "HEAD" {
+		w.Write(playlist)
+	}
+}
+
+// get requests uri with the auth_token.
+// The request is retried once with a refreshed auth_token if radiko rejects it.
+func (p *Proxy) get(ctx context.Context, uri string) (*http.Response, error) {
+	if err := p.tokens.Authorize(ctx); err != nil {
+		return nil, err
+	}
+
+	return hls.NewFetcher(p.tokens).Get(ctx, uri)
+}
+
+// rewrite replaces the uris in the playlist with the proxy's.
+func (p *Proxy) rewrite(body []byte, base string) ([]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(body), []byte("#EXTM3U")) {
+		return nil, errors.New("invalid playlist: " + base)
+	}
+	master := bytes.Contains(body, []byte("#EXT-X-STREAM-INF"))
+
+	var out bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#"):
+			playlist := strings.HasPrefix(line, "#EXT-X-MEDIA:") ||
+				strings.HasPrefix(line, "#EXT-X-I-FRAME-STREAM-INF:")
+			var err error
+			line = uriAttr.ReplaceAllStringFunc(line, func(attr string) string {
+				u, rerr := p.proxyURL(base, uriAttr.FindStringSubmatch(attr)[1], playlist)
+				if rerr != nil {
+					err = rerr
+				}
+				return `URI="` + u + `"`
+			})
+			if err != nil {
+				return nil, err
+			}
+		default:
+			u, err := p.proxyURL(base, line, master)
+			if err != nil {
+				return nil, err
+			}
+			line = u
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// proxyURL returns the path on the proxy for the uri referenced in the playlist at base.
+func (p *Proxy) proxyURL(base, ref string, playlist bool) (string, error) {
+	abs, err := hls.Resolve(base, ref)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(abs)
+	if err != nil {
+		return "", err
+	}
+
+	ext := playlistExt
+	if !playlist {
+		ext = path.Ext(u.Path)
+		if ext == "" || ext == playlistExt {
+			ext = defaultSegmentExt
+		}
+	}
+	return p.BasePath + upstreamPath + base64.RawURLEncoding.EncodeToString([]byte(abs)) + ext, nil
+}
+
+func (p *Proxy) allowed(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := u.Hostname()
+	for _, h := range p.AllowedHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package hlsproxy
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/internal/radikotest"
+)
+
+func newTestProxy(t *testing.T) (*radikotest.Server, *httptest.Server, func()) {
+	fake := radikotest.NewServer()
+	radiko.SetHTTPClient(fake.HTTPClient())
+
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatalf("Failed to construct client: %s", err)
+	}
+	p := New(radiko.NewTokenManager(client))
+	p.BasePath = "/hls"
+	ts := httptest.NewServer(p)
+
+	return fake, ts, func() {
+		ts.Close()
+		fake.Close()
+		radiko.SetHTTPClient(&http.Client{Timeout: 120 * time.Second})
+	}
+}
+
+func get(t *testing.T, uri string) (*http.Response, string) {
+	resp, err := http.Get(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+// uris returns the uri lines of the playlist.
+func uris(playlist string) []string {
+	var l []string
+	for _, line := range strings.Split(playlist, "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			l = append(l, line)
+		}
+	}
+	return l
+}
+
+func TestProxy_Live(t *testing.T) {
+	_, ts, cleanup := newTestProxy(t)
+	defer cleanup()
+
+	resp, master := get(t, ts.URL+"/hls/live/LFR/playlist.m3u8")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != playlistContentType {
+		t.Errorf("unexpected Content-Type: %s", ct)
+	}
+	variants := uris(master)
+	if len(variants) != 1 || !strings.HasPrefix(variants[0], "/hls/upstream/") || !strings.HasSuffix(variants[0], ".m3u8") {
+		t.Fatalf("invalid master playlist: %s", master)
+	}
+
+	_, media := get(t, ts.URL+variants[0])
+	segments := uris(media)
+	if len(segments) != radikotest.LiveSegments {
+		t.Fatalf("invalid media playlist: %s", media)
+	}
+	for _, s := range segments {
+		if !strings.HasSuffix(s, ".aac") {
+			t.Errorf("segment should have .aac extension: %s", s)
+		}
+	}
+
+	resp, body := get(t, ts.URL+segments[0])
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status: %d", resp.StatusCode)
+	}
+	expected := radikotest.Segment("/live/LFR/segments/0.aac")
+	if body != expected {
+		t.Errorf("expected %s, but %s", expected, body)
+	}
+}
+
+func TestProxy_Timeshift(t *testing.T) {
+	fake, ts, cleanup := newTestProxy(t)
+	defer cleanup()
+	// The program from 23:00 to 23:30 has ended at 23:40 JST.
+	fake.SetNow(time.Date(2016, 11, 12, 14, 40, 0, 0, time.UTC))
+
+	_, media := get(t, ts.URL+"/hls/timeshift/LFR/20161112230000/playlist.m3u8")
+	if !strings.Contains(media, "#EXT-X-ENDLIST") {
+		t.Errorf("media playlist should be closed: %s", media)
+	}
+	segments := uris(media)
+	if len(segments) != radikotest.TimeshiftSegments {
+		t.Fatalf("invalid media playlist: %s", media)
+	}
+	_, body := get(t, ts.URL+segments[2])
+	expected := radikotest.Segment("/tf/segments/2.aac")
+	if body != expected {
+		t.Errorf("expected %s, but %s", expected, body)
+	}
+}
+
+func TestProxy_RefreshToken(t *testing.T) {
+	fake, ts, cleanup := newTestProxy(t)
+	defer cleanup()
+
+	_, master := get(t, ts.URL+"/hls/live/LFR/playlist.m3u8")
+	_, media := get(t, ts.URL+uris(master)[0])
+
+	fake.RevokeTokens()
+	resp, body := get(t, ts.URL+uris(media)[0])
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("token should be refreshed, but status %d: %s", resp.StatusCode, body)
+	}
+	if n := fake.Count("/v2/api/auth1"); n != 2 {
+		t.Errorf("expected 2 auth1 requests, but %d", n)
+	}
+}
+
+func TestProxy_RefreshToken_Playlist(t *testing.T) {
+	fake, ts, cleanup := newTestProxy(t)
+	defer cleanup()
+
+	get(t, ts.URL+"/hls/live/LFR/playlist.m3u8")
+	fake.RevokeTokens()
+	resp, body := get(t, ts.URL+"/hls/live/LFR/playlist.m3u8")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("token should be refreshed, but status %d: %s", resp.StatusCode, body)
+	}
+	if n := fake.Count("/v2/api/auth1"); n != 2 {
+		t.Errorf("expected 2 auth1 requests, but %d", n)
+	}
+}
+
+func TestProxy_PlaylistError(t *testing.T) {
+	fake, ts, cleanup := newTestProxy(t)
+	defer cleanup()
+
+	fake.FailNext("/v2/api/playlist_create/", 1)
+	resp, _ := get(t, ts.URL+"/hls/live/LFR/playlist.m3u8")
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected %d, but %d", http.StatusBadGateway, resp.StatusCode)
+	}
+	if n := fake.Count("/v2/api/auth1"); n != 1 {
+		t.Errorf("token should not be refreshed, but %d auth1 requests", n)
+	}
+}
+
+func TestProxy_StreamURLNotFound(t *testing.T) {
+	fake := radikotest.NewServer()
+	defer fake.Close()
+	radiko.SetHTTPClient(fake.HTTPClient())
+	defer radiko.SetHTTPClient(&http.Client{Timeout: 120 * time.Second})
+
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatalf("Failed to construct client: %s", err)
+	}
+	// The fake server provides area-locked urls only.
+	client.SetAreafreePolicy(radiko.AreafreeOnly)
+	ts := httptest.NewServer(New(radiko.NewTokenManager(client)))
+	defer ts.Close()
+
+	resp, _ := get(t, ts.URL+"/live/LFR/playlist.m3u8")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected %d, but %d", http.StatusNotFound, resp.StatusCode)
+	}
+	if n := fake.Count("/v2/api/auth1"); n != 1 {
+		t.Errorf("token should not be refreshed, but %d auth1 requests", n)
+	}
+}
+
+func TestProxy_Errors(t *testing.T) {
+	_, ts, cleanup := newTestProxy(t)
+	defer cleanup()
+
+	disallowed := base64.RawURLEncoding.EncodeToString([]byte("https://example.com/playlist.m3u8"))
+	cases := []struct {
+		path     string
+		expected int
+	}{
+		{"/hls/", http.StatusNotFound},
+		{"/hls/live/LFR", http.StatusNotFound},
+		{"/hls/timeshift/LFR/invalid/playlist.m3u8", http.StatusBadRequest},
+		{"/hls/timeshift/LFR/20161112230100/playlist.m3u8", http.StatusNotFound},
+		{"/hls/upstream/!!!.m3u8", http.StatusBadRequest},
+		{"/hls/upstream/" + disallowed + ".m3u8", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		resp, _ := get(t, ts.URL+c.path)
+		if resp.StatusCode != c.expected {
+			t.Errorf("%s: expected %d, but %d", c.path, c.expected, resp.StatusCode)
+		}
+	}
+}
+
+func TestProxy_Rewrite(t *testing.T) {
+	p := &Proxy{}
+	playlist := "#EXTM3U\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"key.bin\"\n" +
+		"#EXTINF:5,\n" +
+		"segments/1?foo=bar\n"
+	b, err := p.rewrite([]byte(playlist), "https://radiko.jp/tf/medialist")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enc := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	expected := "#EXTM3U\n" +
+		"#EXT-X-KEY:METHOD=AES-128,URI=\"/upstream/" + enc("https://radiko.jp/tf/key.bin") + ".bin\"\n" +
+		"#EXTINF:5,\n" +
+		"/upstream/" + enc("https://radiko.jp/tf/segments/1?foo=bar") + ".aac\n"
+	if string(b) != expected {
+		t.Errorf("expected %s, but %s", expected, b)
+	}
+
+	if _, err := p.rewrite([]byte("<html></html>"), "https://radiko.jp/"); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
@@ -0,0 +1,90 @@
+package htmltext
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	emailPattern   = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	urlPattern     = regexp.MustCompile(`https?://[!-~]+`)
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_/])[#＃]([\p{L}\p{N}_]+)`)
+)
+
+// urlTrailer is the punctuation which ends a url in text rather than being part of it.
+const urlTrailer = `.,;:!?)]}'"`
+
+// Entities is the structured data in an HTML fragment.
+type Entities struct {
+	// Images are the urls of <img> elements.
+	Images []string
+	// Links are the http(s) urls of <a> elements and in the text.
+	Links []string
+	// Emails are the addresses of mailto links and in the text.
+	Emails []string
+	// Hashtags are the hashtags in the text, such as "#radiko".
+	Hashtags []string
+}
+
+// Extract returns the structured data in the HTML fragment s.
+// Each list is in the order of appearance without duplicates.
+func Extract(s string) Entities {
+	var e Entities
+	z := html.NewTokenizer(strings.NewReader(s))
+	for done := false; !done; {
+		switch z.Next() {
+		case html.ErrorToken:
+			done = true
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "a":
+				href := attrValue(t, "href")
+				switch {
+				case safeURL(href, "http", "https"):
+					e.Links = appendUnique(e.Links, href)
+				case safeURL(href, "mailto"):
+					if u, err := url.Parse(href); err == nil && u.Opaque != "" {
+						addr, _ := url.PathUnescape(u.Opaque)
+						e.Emails = appendUnique(e.Emails, addr)
+					}
+				}
+			case "img":
+				if src := attrValue(t, "src"); safeURL(src, "http", "https") {
+					e.Images = appendUnique(e.Images, src)
+				}
+			}
+		}
+	}
+
+	text := PlainText(s)
+	for _, u := range urlPattern.FindAllString(text, -1) {
+		e.Links = appendUnique(e.Links, strings.TrimRight(u, urlTrailer))
+	}
+	for _, addr := range emailPattern.FindAllString(text, -1) {
+		e.Emails = appendUnique(e.Emails, addr)
+	}
+	for _, m := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		e.Hashtags = appendUnique(e.Hashtags, "#"+m[1])
+	}
+	return e
+}
+
+func attrValue(t html.Token, key string) string {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func appendUnique(l []string, s string) []string {
+	if s == "" || contains(l, s) {
+		return l
+	}
+	return append(l, s)
+}
@@ -0,0 +1,29 @@
+package htmltext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	in := `<img src='http://static.tbsradio.jp/main.jpg' style="max-width: 200px;"><br />` +
+		`twitterハッシュタグは「<a href="http://twitter.com/#!/search/%23utamaru">#utamaru</a>」<br/>` +
+		`メール：<a href="mailto:utamaru@tbs.co.jp">utamaru@tbs.co.jp</a><br/>` +
+		`ラジオクラウド：<a href="https://radiocloud.jp/archive/utamaru">https://radiocloud.jp/archive/utamaru</a><br/>` +
+		`詳しくは https://example.com/page. まで、ご意見は info@example.co.jp へ。番組ハッシュタグは　＃大倉くんと高橋くん　です！` +
+		`<a href="javascript:alert(1)">x</a><img src="/relative.jpg">`
+
+	expected := Entities{
+		Images:   []string{"http://static.tbsradio.jp/main.jpg"},
+		Links:    []string{"http://twitter.com/#!/search/%23utamaru", "https://radiocloud.jp/archive/utamaru", "https://example.com/page"},
+		Emails:   []string{"utamaru@tbs.co.jp", "info@example.co.jp"},
+		Hashtags: []string{"#utamaru", "#大倉くんと高橋くん"},
+	}
+	if actual := Extract(in); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %+v, but %+v", expected, actual)
+	}
+
+	if e := Extract(""); !reflect.DeepEqual(e, Entities{}) {
+		t.Errorf("expected empty, but %+v", e)
+	}
+}
@@ -0,0 +1,94 @@
+// Package htmltext converts HTML fragments in program meta-info (Prog.Desc and Prog.Info)
+// into plain text or a safe subset of HTML, and extracts structured data from them.
+package htmltext
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockTags are the elements which break lines in plain text.
+var blockTags = map[string]bool{
+	"p": true, "div": true, "ul": true, "ol": true, "li": true,
+	"table": true, "tr": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// skipTags are the elements whose content is not text.
+var skipTags = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true, "template": true,
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// PlainText returns the text content of the HTML fragment s.
+// <br> and block elements are converted into newlines,
+// and runs of whitespace are collapsed.
+func PlainText(s string) string {
+	var b strings.Builder
+	skip := 0
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		name, _ := z.TagName()
+		tag := string(name)
+		switch tt {
+		case html.ErrorToken:
+			return cleanLines(b.String())
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(collapseSpace(string(z.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch {
+			case skipTags[tag] && tt == html.StartTagToken:
+				skip++
+			case tag == "br":
+				b.WriteByte('\n')
+			case blockTags[tag]:
+				b.WriteString("\n\n")
+			}
+		case html.EndTagToken:
+			switch {
+			case skipTags[tag] && skip > 0:
+				skip--
+			case blockTags[tag]:
+				b.WriteString("\n\n")
+			}
+		}
+	}
+}
+
+// collapseSpace replaces runs of HTML whitespace with a space.
+// Other spaces such as the ideographic space are kept.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+// cleanLines trims each line and removes consecutive blank lines.
+func cleanLines(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.Trim(l, " ")
+	}
+	s = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(s)
+}
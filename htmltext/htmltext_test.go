@@ -0,0 +1,23 @@
+package htmltext
+
+import "testing"
+
+func TestPlainText(t *testing.T) {
+	cases := []struct {
+		in, expected string
+	}{
+		{"", ""},
+		{"plain", "plain"},
+		{`<img src='a.jpg'><br /><br/>text &amp; <a href="#">link</a>`, "text & link"},
+		{"line1<br>line2", "line1\nline2"},
+		{"  a \n\t b　c ", "a b　c"},
+		{"<p>para1</p><p>para2</p><br><br><br>end", "para1\n\npara2\n\nend"},
+		{"<ul><li>one</li><li>two</li></ul>", "one\n\ntwo"},
+		{"before<script>alert(1)</script><style>p{}</style>after", "beforeafter"},
+	}
+	for _, c := range cases {
+		if actual := PlainText(c.in); c.expected != actual {
+			t.Errorf("expected %q, but %q", c.expected, actual)
+		}
+	}
+}
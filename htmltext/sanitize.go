@@ -0,0 +1,149 @@
+package htmltext
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// allowedTags are the elements kept by Sanitize, and their allowed attributes.
+var allowedTags = map[string][]string{
+	"a":      {"href", "title"},
+	"b":      nil,
+	"br":     nil,
+	"em":     nil,
+	"i":      nil,
+	"img":    {"src", "alt", "width", "height"},
+	"li":     nil,
+	"ol":     nil,
+	"p":      nil,
+	"strong": nil,
+	"u":      nil,
+	"ul":     nil,
+}
+
+var voidTags = map[string]bool{"br": true, "img": true}
+
+// Sanitize returns a safe subset of the HTML fragment s,
+// which may be embedded in web pages.
+//
+// Only basic formatting elements, links and images are kept.
+// Links and images must be absolute http(s) urls (or mailto for links),
+// and links get rel="nofollow noopener noreferrer".
+// Other elements are removed, keeping their text except for scripts and styles.
+func Sanitize(s string) string {
+	var b strings.Builder
+	var open []string
+	skip := 0
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			for i := len(open) - 1; i >= 0; i-- {
+				b.WriteString("</" + open[i] + ">")
+			}
+			return b.String()
+		case html.TextToken:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(string(z.Text())))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if skipTags[t.Data] {
+				if tt == html.StartTagToken {
+					skip++
+				}
+				continue
+			}
+			if skip > 0 {
+				continue
+			}
+			tag, ok := sanitizeTag(t)
+			if !ok {
+				continue
+			}
+			b.WriteString(tag)
+			if !voidTags[t.Data] {
+				open = append(open, t.Data)
+			}
+		case html.EndTagToken:
+			t := z.Token()
+			if skipTags[t.Data] {
+				if skip > 0 {
+					skip--
+				}
+				continue
+			}
+			// Close the element and the elements opened in it.
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != t.Data {
+					continue
+				}
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+				open = open[:i]
+				break
+			}
+		}
+	}
+}
+
+// sanitizeTag returns the start tag with the allowed attributes,
+// or false if the element is not allowed.
+func sanitizeTag(t html.Token) (string, bool) {
+	allowed, ok := allowedTags[t.Data]
+	if !ok {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString("<" + t.Data)
+	hasSrc := false
+	for _, a := range t.Attr {
+		if !contains(allowed, a.Key) {
+			continue
+		}
+		v := strings.TrimSpace(a.Val)
+		switch a.Key {
+		case "href":
+			if !safeURL(v, "http", "https", "mailto") {
+				continue
+			}
+		case "src":
+			if !safeURL(v, "http", "https") {
+				continue
+			}
+			hasSrc = true
+		}
+		b.WriteString(" " + a.Key + `="` + html.EscapeString(v) + `"`)
+	}
+	if t.Data == "img" && !hasSrc {
+		return "", false
+	}
+	if t.Data == "a" {
+		b.WriteString(` rel="nofollow noopener noreferrer"`)
+	}
+	b.WriteString(">")
+	return b.String(), true
+}
+
+// safeURL reports whether s is an absolute url of the schemes.
+func safeURL(s string, schemes ...string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return contains(schemes, strings.ToLower(u.Scheme))
+}
+
+func contains(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+package htmltext
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	cases := []struct {
+		in, expected string
+	}{
+		{"", ""},
+		{"a & b < c", "a &amp; b &lt; c"},
+		{"line1<br/>line2", "line1<br>line2"},
+		{`<img src='http://example.com/a.jpg' style="max-width: 200px;" onerror="alert(1)">`, `<img src="http://example.com/a.jpg">`},
+		{`<img src="javascript:alert(1)">text`, "text"},
+		{`<a href="http://example.com/?a=1&amp;b=2" onclick="x()">link</a>`, `<a href="http://example.com/?a=1&amp;b=2" rel="nofollow noopener noreferrer">link</a>`},
+		{`<a href="javascript:alert(1)">link</a>`, `<a rel="nofollow noopener noreferrer">link</a>`},
+		{`<a href="mailto:a@example.com">a@example.com</a>`, `<a href="mailto:a@example.com" rel="nofollow noopener noreferrer">a@example.com</a>`},
+		{"<div><b>bold<i>both</b>italic</i></div>", "<b>bold<i>both</i></b>italic"},
+		{"<p>unclosed<strong>strong", "<p>unclosed<strong>strong</strong></p>"},
+		{"before<script>alert('<b>')</script>after<iframe src='x'></iframe>", "beforeafter"},
+	}
+	for _, c := range cases {
+		if actual := Sanitize(c.in); c.expected != actual {
+			t.Errorf("expected %q, but %q", c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// Package icecast pushes radiko's live streams to Icecast or SHOUTcast servers as a source.
+package icecast
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Protocol is the protocol of connecting to the server as a source.
+type Protocol int
+
+const (
+	// ProtocolPUT is the HTTP PUT protocol of Icecast 2.4 or later.
+	ProtocolPUT Protocol = iota
+	// ProtocolSOURCE is the legacy SOURCE protocol of Icecast.
+	ProtocolSOURCE
+	// ProtocolShoutcast is the SHOUTcast v1 protocol.
+	// The source connects to the port next to the server's one.
+	ProtocolShoutcast
+)
+
+const (
+	defaultUser        = "source"
+	defaultContentType = "audio/aac"
+	userAgent          = "go-radiko"
+)
+
+// ErrUnauthorized is returned when the server rejects the credentials.
+var ErrUnauthorized = errors.New("icecast: unauthorized")
+
+// Source is the configuration of a source connection.
+type Source struct {
+	// Addr is the "host:port" of the server.
+	Addr string
+	// Mount is the mount point, e.g. "/radiko.aac". It is ignored by SHOUTcast.
+	Mount string
+	// User is the source user. Defaults to "source".
+	User     string
+	Password string
+	Protocol Protocol
+
+	// ContentType defaults to "audio/aac".
+	ContentType string
+	Name        string
+	Description string
+	Genre       string
+	URL         string
+	// Public lists the stream in the server's directory.
+	Public bool
+
+	// HTTPClient is used for metadata updates. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Connect connects to the server as a source.
+// Audio data written to the returned connection is broadcast by the server.
+func (s *Source) Connect(ctx context.Context) (io.WriteCloser, error) {
+	addr := s.Addr
+	if s.Protocol == ProtocolShoutcast {
+		var err error
+		if addr, err = nextPort(addr); err != nil {
+			return nil, err
+		}
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	// Abort the handshake when ctx is done.
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	if s.Protocol == ProtocolShoutcast {
+		err = s.handshakeShoutcast(conn)
+	} else {
+		err = s.handshakeIcecast(conn)
+	}
+	close(stop)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (s *Source) handshakeIcecast(conn net.Conn) error {
+	method := "PUT"
+	if s.Protocol == ProtocolSOURCE {
+		method = "SOURCE"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s HTTP/1.1\r\n", method, s.mount())
+	fmt.Fprintf(&b, "Host: %s\r\n", s.Addr)
+	fmt.Fprintf(&b, "Authorization: Basic %s\r\n", basicAuth(s.user(), s.Password))
+	fmt.Fprintf(&b, "User-Agent: %s\r\n", userAgent)
+	fmt.Fprintf(&b, "Content-Type: %s\r\n", s.contentType())
+	s.writeHeaders(&b, "Ice-")
+	b.WriteString("\r\n")
+	if _, err := io.WriteString(conn, b.String()); err != nil {
+		return err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusContinue:
+		return nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrUnauthorized
+	}
+	return fmt.Errorf("icecast: failed to connect as a source: %s", resp.Status)
+}
+
+func (s *Source) handshakeShoutcast(conn net.Conn) error {
+	if _, err := io.WriteString(conn, s.Password+"\r\n"); err != nil {
+		return err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "OK") {
+		return ErrUnauthorized
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "content-type:%s\r\n", s.contentType())
+	s.writeHeaders(&b, "icy-")
+	b.WriteString("\r\n")
+	_, err = io.WriteString(conn, b.String())
+	return err
+}
+
+func (s *Source) writeHeaders(b *strings.Builder, prefix string) {
+	public := "0"
+	if s.Public {
+		public = "1"
+	}
+	fmt.Fprintf(b, "%spublic: %s\r\n", prefix, public)
+	for _, h := range []struct{ key, value string }{
+		{"name", s.Name},
+		{"description", s.Description},
+		{"genre", s.Genre},
+		{"url", s.URL},
+	} {
+		if h.value != "" {
+			fmt.Fprintf(b, "%s%s: %s\r\n", prefix, h.key, sanitizeHeader(h.value))
+		}
+	}
+}
+
+// SetMetadata updates the title of the stream (StreamTitle of ICY metadata).
+func (s *Source) SetMetadata(ctx context.Context, title string) error {
+	u := &url.URL{Scheme: "http", Host: s.Addr}
+	q := url.Values{}
+	q.Set("mode", "updinfo")
+	q.Set("song", title)
+	if s.Protocol == ProtocolShoutcast {
+		u.Path = "/admin.cgi"
+		q.Set("pass", s.Password)
+	} else {
+		u.Path = "/admin/metadata"
+		q.Set("mount", s.mount())
+		q.Set("charset", "UTF-8")
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	// SHOUTcast v1 rejects requests without a browser-like User-Agent.
+	req.Header.Set("User-Agent", "Mozilla/5.0 ("+userAgent+")")
+	if s.Protocol != ProtocolShoutcast {
+		req.SetBasicAuth(s.user(), s.Password)
+	}
+
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		return ErrUnauthorized
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return fmt.Errorf("icecast: failed to update metadata: %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *Source) mount() string {
+	if strings.HasPrefix(s.Mount, "/") {
+		return s.Mount
+	}
+	return "/" + s.Mount
+}
+
+func (s *Source) user() string {
+	if s.User == "" {
+		return defaultUser
+	}
+	return s.User
+}
+
+func (s *Source) contentType() string {
+	if s.ContentType == "" {
+		return defaultContentType
+	}
+	return s.ContentType
+}
+
+func basicAuth(user, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+}
+
+// sanitizeHeader removes line breaks which would end the header.
+func sanitizeHeader(v string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(v)
+}
+
+// nextPort returns the address whose port is next to addr's.
+func nextPort(addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(n+1)), nil
+}
@@ -0,0 +1,240 @@
+package icecast
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer is a fake Icecast server which accepts a source and metadata updates.
+type fakeServer struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	requests []*http.Request
+	body     strings.Builder
+	songs    []string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	br := bufio.NewReader(conn)
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	if user, pass, ok := req.BasicAuth(); !ok || user != "source" || pass != "hackme" {
+		io.WriteString(conn, "HTTP/1.1 401 Unauthorized\r\nContent-Length: 0\r\n\r\n")
+		return
+	}
+
+	switch {
+	case req.Method == "PUT" || req.Method == "SOURCE":
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\n\r\n")
+		buf := make([]byte, 256)
+		for {
+			n, err := br.Read(buf)
+			s.mu.Lock()
+			s.body.Write(buf[:n])
+			s.mu.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	case req.URL.Path == "/admin/metadata":
+		s.mu.Lock()
+		s.songs = append(s.songs, req.URL.Query().Get("song"))
+		s.mu.Unlock()
+		io.WriteString(conn, "HTTP/1.1 200 OK\r\nContent-Length: 0\r\nConnection: close\r\n\r\n")
+	default:
+		io.WriteString(conn, "HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\n\r\n")
+	}
+}
+
+func (s *fakeServer) Close() {
+	s.ln.Close()
+}
+
+func (s *fakeServer) Addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *fakeServer) lastRequest() *http.Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[len(s.requests)-1]
+}
+
+func (s *fakeServer) received() (string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.body.String(), append([]string(nil), s.songs...)
+}
+
+func TestSource_Connect(t *testing.T) {
+	fake := newFakeServer(t)
+	defer fake.Close()
+
+	for _, p := range []Protocol{ProtocolPUT, ProtocolSOURCE} {
+		src := &Source{
+			Addr:     fake.Addr(),
+			Mount:    "lfr.aac",
+			Password: "hackme",
+			Protocol: p,
+			Name:     "LFR\r\nX-Injected: 1",
+		}
+		conn, err := src.Connect(context.Background())
+		if err != nil {
+			t.Fatalf("Failed to connect: %s", err)
+		}
+		conn.Close()
+
+		req := fake.lastRequest()
+		if req.URL.Path != "/lfr.aac" {
+			t.Errorf("expected /lfr.aac, but %s", req.URL.Path)
+		}
+		if ct := req.Header.Get("Content-Type"); ct != defaultContentType {
+			t.Errorf("expected %s, but %s", defaultContentType, ct)
+		}
+		if name := req.Header.Get("Ice-Name"); name != "LFR  X-Injected: 1" {
+			t.Errorf("unexpected Ice-Name: %s", name)
+		}
+		if req.Header.Get("X-Injected") != "" {
+			t.Error("Header should not be injected.")
+		}
+	}
+	if req := fake.lastRequest(); req.Method != "SOURCE" {
+		t.Errorf("expected SOURCE, but %s", req.Method)
+	}
+
+	src := &Source{Addr: fake.Addr(), Mount: "/lfr.aac", Password: "invalid"}
+	if _, err := src.Connect(context.Background()); err != ErrUnauthorized {
+		t.Errorf("expected %v, but %v", ErrUnauthorized, err)
+	}
+}
+
+func TestSource_handshakeShoutcast(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+		br := bufio.NewReader(server)
+		if line, _ := br.ReadString('\n'); line == "hackme\r\n" {
+			io.WriteString(server, "OK2\r\nicy-caps:11\r\n\r\n")
+		} else {
+			io.WriteString(server, "invalid password\r\n")
+			return
+		}
+		for {
+			line, err := br.ReadString('\n')
+			if err != nil || line == "\r\n" {
+				return
+			}
+		}
+	}()
+
+	src := &Source{Password: "hackme", Protocol: ProtocolShoutcast}
+	if err := src.handshakeShoutcast(client); err != nil {
+		t.Errorf("Failed to handshake: %s", err)
+	}
+
+	client, server = net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		bufio.NewReader(server).ReadString('\n')
+		io.WriteString(server, "invalid password\r\n")
+	}()
+	src.Password = "invalid"
+	if err := src.handshakeShoutcast(client); err != ErrUnauthorized {
+		t.Errorf("expected %v, but %v", ErrUnauthorized, err)
+	}
+}
+
+func TestSource_SetMetadata(t *testing.T) {
+	fake := newFakeServer(t)
+	defer fake.Close()
+
+	src := &Source{Addr: fake.Addr(), Mount: "/lfr.aac", Password: "hackme"}
+	if err := src.SetMetadata(context.Background(), "タイトル - 出演者"); err != nil {
+		t.Fatalf("Failed to set metadata: %s", err)
+	}
+	_, songs := fake.received()
+	if len(songs) != 1 || songs[0] != "タイトル - 出演者" {
+		t.Errorf("unexpected songs: %v", songs)
+	}
+	req := fake.lastRequest()
+	if m := req.URL.Query().Get("mount"); m != "/lfr.aac" {
+		t.Errorf("expected /lfr.aac, but %s", m)
+	}
+
+	src.Password = "invalid"
+	if err := src.SetMetadata(context.Background(), "title"); err != ErrUnauthorized {
+		t.Errorf("expected %v, but %v", ErrUnauthorized, err)
+	}
+}
+
+func TestNextPort(t *testing.T) {
+	addr, err := nextPort("localhost:8000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != "localhost:8001" {
+		t.Errorf("expected localhost:8001, but %s", addr)
+	}
+	if _, err := nextPort("localhost"); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// sources returns the number of the source connections.
+func (s *fakeServer) sources() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, req := range s.requests {
+		if req.Method == "PUT" || req.Method == "SOURCE" {
+			n++
+		}
+	}
+	return n
+}
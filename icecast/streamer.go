@@ -0,0 +1,134 @@
+package icecast
+
+import (
+	"context"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/internal/hls"
+)
+
+const (
+	// defaultRetryInterval is the interval of reloading the programs on the air
+	// when the current program is unknown.
+	defaultRetryInterval = time.Minute
+)
+
+// Streamer pushes a live station to a Source,
+// updating the metadata whenever the program changes.
+type Streamer struct {
+	Source *Source
+	// Title formats the metadata of the program. Defaults to DefaultTitle.
+	Title func(radiko.Station, radiko.Prog) string
+	// PollInterval is the interval of reloading the live playlist.
+	// Zero means the target duration of the playlist.
+	PollInterval time.Duration
+	// RetryInterval is the interval of reloading the programs on the air
+	// when the current program is unknown.
+	RetryInterval time.Duration
+
+	tokens *radiko.TokenManager
+	client *radiko.Client
+	now    func() time.Time
+}
+
+// NewStreamer returns a new Streamer which accesses radiko with the managed client.
+func NewStreamer(tokens *radiko.TokenManager, src *Source) *Streamer {
+	return &Streamer{
+		Source:        src,
+		Title:         DefaultTitle,
+		RetryInterval: defaultRetryInterval,
+		tokens:        tokens,
+		client:        tokens.Client(),
+		now:           tokens.Client().Now,
+	}
+}
+
+// DefaultTitle formats the metadata as "title - performers".
+func DefaultTitle(_ radiko.Station, p radiko.Prog) string {
+	if p.Pfm == "" {
+		return p.Title
+	}
+	return p.Title + " - " + p.Pfm
+}
+
+// Stream pushes the live stream of the station until ctx is done or an error occurs.
+func (s *Streamer) Stream(ctx context.Context, stationID string) error {
+	if err := s.tokens.Authorize(ctx); err != nil {
+		return err
+	}
+	uri, err := s.client.LivePlaylistM3U8(ctx, stationID)
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.Source.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go s.updateMetadata(ctx, stationID)
+
+	// The fetcher refreshes the auth_token when radiko rejects it,
+	// so that the source connection is kept over the lifetime of a token.
+	f := hls.NewFetcher(s.tokens)
+	f.PollInterval = s.PollInterval
+	return f.Copy(ctx, conn, uri, hls.Options{})
+}
+
+// updateMetadata sets the metadata of the current program,
+// and again at the end of each program.
+func (s *Streamer) updateMetadata(ctx context.Context, stationID string) {
+	var title string
+	for {
+		wait := s.RetryInterval
+		station, prog, ok := s.current(ctx, stationID)
+		if ok {
+			if t := s.Title(station, prog); t != title {
+				if err := s.Source.SetMetadata(ctx, t); err == nil {
+					title = t
+				}
+			}
+			if to, err := prog.EndTime(); err == nil && to.After(s.now()) && title != "" {
+				wait = to.Sub(s.now())
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// current returns the program of the station on the air.
+func (s *Streamer) current(ctx context.Context, stationID string) (radiko.Station, radiko.Prog, bool) {
+	stations, err := s.client.GetNowPrograms(ctx)
+	if err != nil {
+		return radiko.Station{}, radiko.Prog{}, false
+	}
+	now := s.now()
+	for _, st := range stations {
+		if st.ID != stationID {
+			continue
+		}
+		for _, p := range st.Programs() {
+			ft, err := p.StartTime()
+			if err != nil {
+				continue
+			}
+			to, err := p.EndTime()
+			if err != nil {
+				continue
+			}
+			if !now.Before(ft) && now.Before(to) {
+				return st, p, true
+			}
+		}
+	}
+	return radiko.Station{}, radiko.Prog{}, false
+}
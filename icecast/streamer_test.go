@@ -0,0 +1,110 @@
+package icecast
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/internal/radikotest"
+)
+
+func TestStreamer_Stream(t *testing.T) {
+	fake := radikotest.NewServer()
+	defer fake.Close()
+	radiko.SetHTTPClient(fake.HTTPClient())
+	defer radiko.SetHTTPClient(&http.Client{Timeout: 120 * time.Second})
+
+	ice := newFakeServer(t)
+	defer ice.Close()
+
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatalf("Failed to construct client: %s", err)
+	}
+	s := NewStreamer(radiko.NewTokenManager(client), &Source{
+		Addr:     ice.Addr(),
+		Mount:    "/lfr.aac",
+		Password: "hackme",
+	})
+	s.PollInterval = 10 * time.Millisecond
+	// The first program ends 100ms after the streamer starts.
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	base := time.Date(2016, 11, 12, 23, 29, 59, 900000000, loc)
+	started := time.Now()
+	s.now = func() time.Time { return base.Add(time.Since(started)) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Stream(ctx, "LFR") }()
+
+	waitFor(t, func() bool {
+		body, songs := ice.received()
+		return strings.Contains(body, radikotest.Segment("/live/LFR/segments/3.aac")) && len(songs) >= 2
+	})
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, but %v", context.Canceled, err)
+	}
+
+	_, songs := ice.received()
+	expected := []string{
+		"中居正広のSome girl’ SMAP - 中居正広（ＳＭＡＰ）",
+		"オールナイトニッポンサタデースペシャル 大倉くんと高橋くん - 大倉忠義＆高橋優",
+	}
+	for i, e := range expected {
+		if songs[i] != e {
+			t.Errorf("expected %s, but %s", e, songs[i])
+		}
+	}
+}
+
+func TestStreamer_Stream_RefreshToken(t *testing.T) {
+	fake := radikotest.NewServer()
+	defer fake.Close()
+	radiko.SetHTTPClient(fake.HTTPClient())
+	defer radiko.SetHTTPClient(&http.Client{Timeout: 120 * time.Second})
+
+	ice := newFakeServer(t)
+	defer ice.Close()
+
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatalf("Failed to construct client: %s", err)
+	}
+	s := NewStreamer(radiko.NewTokenManager(client), &Source{
+		Addr:     ice.Addr(),
+		Mount:    "/lfr.aac",
+		Password: "hackme",
+	})
+	s.PollInterval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Stream(ctx, "LFR") }()
+
+	waitFor(t, func() bool {
+		body, _ := ice.received()
+		return strings.Contains(body, radikotest.Segment("/live/LFR/segments/2.aac"))
+	})
+	// The auth_token expires in the middle of the stream.
+	fake.RevokeTokens()
+	waitFor(t, func() bool {
+		body, _ := ice.received()
+		return strings.Contains(body, radikotest.Segment("/live/LFR/segments/8.aac"))
+	})
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, but %v", context.Canceled, err)
+	}
+
+	if n := fake.Count("/v2/api/auth1"); n != 2 {
+		t.Errorf("Should refresh the auth_token once: auth1=%d", n)
+	}
+	if n := ice.sources(); n != 1 {
+		t.Errorf("Should keep the mount: %d source connections", n)
+	}
+}
@@ -0,0 +1,228 @@
+// Package id3 writes ID3v2.4 tags for raw AAC (ADTS) recordings.
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	// textEncodingUTF8 is the ID3v2.4 text encoding byte for UTF-8.
+	textEncodingUTF8 = 0x03
+
+	// maxSyncsafe is the largest value representable by a 28bit sync-safe integer.
+	maxSyncsafe = 1<<28 - 1
+
+	// noOffset means that CHAP frames do not carry byte offsets.
+	noOffset = 0xffffffff
+
+	// maxChapters is the number of entries a CTOC frame can hold.
+	maxChapters = 255
+
+	dateLayout = "2006-01-02T15:04:05"
+)
+
+// Tag represents an ID3v2.4 tag.
+type Tag struct {
+	Title       string
+	SubTitle    string
+	Performers  string
+	Station     string
+	Date        time.Time
+	Length      time.Duration
+	URL         string
+	Description string
+	Chapters    []Chapter
+}
+
+// Chapter represents a chapter (CHAP frame) in a recording.
+// Start and End are offsets from the beginning of the recording.
+type Chapter struct {
+	ID    string
+	Title string
+	Start time.Duration
+	End   time.Duration
+}
+
+// Bytes returns the binary representation of the tag.
+func (t *Tag) Bytes() ([]byte, error) {
+	if len(t.Chapters) > maxChapters {
+		return nil, errors.New("too many chapters")
+	}
+
+	var frames bytes.Buffer
+	for _, f := range t.frames() {
+		if err := writeFrame(&frames, f.id, f.body); err != nil {
+			return nil, err
+		}
+	}
+	if frames.Len() > maxSyncsafe {
+		return nil, errors.New("id3 tag is too large")
+	}
+
+	var b bytes.Buffer
+	b.WriteString("ID3")
+	b.Write([]byte{0x04, 0x00, 0x00}) // v2.4.0, no flags
+	b.Write(syncsafe(uint32(frames.Len())))
+	b.Write(frames.Bytes())
+	return b.Bytes(), nil
+}
+
+// WriteTo writes the tag to w.
+func (t *Tag) WriteTo(w io.Writer) (int64, error) {
+	b, err := t.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// WriteFile prepends the tag to the recording at path, keeping the mode of the file.
+func WriteFile(path string, t *Tag) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".id3-*")
+	if err != nil {
+		return err
+	}
+
+	// TempFile creates the file with 0600.
+	err = tmp.Chmod(fi.Mode())
+	if err == nil {
+		_, err = t.WriteTo(tmp)
+	}
+	if err == nil {
+		_, err = io.Copy(tmp, src)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+type frame struct {
+	id   string
+	body []byte
+}
+
+func (t *Tag) frames() []frame {
+	var frames []frame
+	addText := func(id, s string) {
+		if s != "" {
+			frames = append(frames, frame{id, textFrame(s)})
+		}
+	}
+
+	addText("TIT2", t.Title)
+	addText("TIT3", t.SubTitle)
+	addText("TPE1", t.Performers)
+	addText("TRSN", t.Station)
+	addText("TPUB", t.Station)
+	if !t.Date.IsZero() {
+		addText("TDRC", t.Date.Format(dateLayout))
+	}
+	if t.Length > 0 {
+		addText("TLEN", strconv.FormatInt(t.Length.Milliseconds(), 10))
+	}
+	if t.URL != "" {
+		frames = append(frames, frame{"WXXX", urlFrame(t.URL)})
+	}
+	if t.Description != "" {
+		frames = append(frames, frame{"COMM", commentFrame(t.Description)})
+	}
+
+	if len(t.Chapters) > 0 {
+		frames = append(frames, frame{"CTOC", tocFrame(t.Chapters)})
+		for _, c := range t.Chapters {
+			frames = append(frames, frame{"CHAP", chapterFrame(c)})
+		}
+	}
+	return frames
+}
+
+func writeFrame(w *bytes.Buffer, id string, body []byte) error {
+	if len(body) > maxSyncsafe {
+		return errors.New("id3 frame is too large: " + id)
+	}
+	w.WriteString(id)
+	w.Write(syncsafe(uint32(len(body))))
+	w.Write([]byte{0x00, 0x00}) // no flags
+	w.Write(body)
+	return nil
+}
+
+func textFrame(s string) []byte {
+	b := []byte{textEncodingUTF8}
+	return append(b, s...)
+}
+
+// urlFrame returns a WXXX body with an empty description.
+func urlFrame(u string) []byte {
+	b := []byte{textEncodingUTF8, 0x00}
+	return append(b, u...)
+}
+
+// commentFrame returns a COMM body in Japanese with an empty description.
+func commentFrame(s string) []byte {
+	b := []byte{textEncodingUTF8, 'j', 'p', 'n', 0x00}
+	return append(b, s...)
+}
+
+func tocFrame(chapters []Chapter) []byte {
+	var b bytes.Buffer
+	b.WriteString("toc")
+	b.WriteByte(0x00)
+	b.WriteByte(0x03) // top-level, ordered
+	b.WriteByte(byte(len(chapters)))
+	for _, c := range chapters {
+		b.WriteString(c.ID)
+		b.WriteByte(0x00)
+	}
+	return b.Bytes()
+}
+
+func chapterFrame(c Chapter) []byte {
+	var b bytes.Buffer
+	b.WriteString(c.ID)
+	b.WriteByte(0x00)
+	binary.Write(&b, binary.BigEndian, uint32(c.Start.Milliseconds()))
+	binary.Write(&b, binary.BigEndian, uint32(c.End.Milliseconds()))
+	binary.Write(&b, binary.BigEndian, uint32(noOffset))
+	binary.Write(&b, binary.BigEndian, uint32(noOffset))
+	if c.Title != "" {
+		writeFrame(&b, "TIT2", textFrame(c.Title))
+	}
+	return b.Bytes()
+}
+
+// syncsafe encodes n as a 28bit sync-safe integer.
+func syncsafe(n uint32) []byte {
+	return []byte{
+		byte(n>>21) & 0x7f,
+		byte(n>>14) & 0x7f,
+		byte(n>>7) & 0x7f,
+		byte(n) & 0x7f,
+	}
+}
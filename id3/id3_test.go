@@ -0,0 +1,102 @@
+package id3
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTag_Bytes(t *testing.T) {
+	tag := &Tag{
+		Title:   "title",
+		Station: "ニッポン放送",
+		Date:    time.Date(2016, 11, 12, 23, 0, 0, 0, time.UTC),
+		Length:  30 * time.Minute,
+		Chapters: []Chapter{
+			{ID: "chp0", Title: "first", Start: 0, End: time.Minute},
+			{ID: "chp1", Title: "second", Start: time.Minute, End: 2 * time.Minute},
+		},
+	}
+	b, err := tag.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(b, []byte{'I', 'D', '3', 0x04, 0x00, 0x00}) {
+		t.Errorf("invalid header: %v", b[:10])
+	}
+	if size := unsyncsafe(b[6:10]); size != len(b)-10 {
+		t.Errorf("expected size %d, but %d", len(b)-10, size)
+	}
+	for _, id := range []string{"TIT2", "TRSN", "TDRC", "TLEN", "CTOC", "CHAP"} {
+		if !bytes.Contains(b, []byte(id)) {
+			t.Errorf("missing frame: %s", id)
+		}
+	}
+	if bytes.Contains(b, []byte("COMM")) {
+		t.Error("Should not contain an empty frame.")
+	}
+}
+
+func TestTag_TooManyChapters(t *testing.T) {
+	tag := &Tag{Chapters: make([]Chapter, maxChapters+1)}
+	if _, err := tag.Bytes(); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test-go-radiko-id3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	audio := []byte{0xff, 0xf1, 0x50, 0x80}
+	path := filepath.Join(dir, "test.aac")
+	if err := ioutil.WriteFile(path, audio, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tag := &Tag{Title: "title"}
+	if err := WriteFile(path, tag); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := tag.Bytes()
+	if !bytes.Equal(b, append(expected, audio...)) {
+		t.Errorf("unexpected file content: %v", b)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0644 {
+		t.Errorf("Should keep the mode of the file: %v", fi.Mode())
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Should not leave the temporary file: %d files", len(files))
+	}
+}
+
+func TestSyncsafe(t *testing.T) {
+	for _, n := range []int{0, 127, 128, 300, maxSyncsafe} {
+		if actual := unsyncsafe(syncsafe(uint32(n))); n != actual {
+			t.Errorf("expected %d, but %d", n, actual)
+		}
+	}
+}
+
+func unsyncsafe(b []byte) int {
+	return int(b[0])<<21 | int(b[1])<<14 | int(b[2])<<7 | int(b[3])
+}
@@ -0,0 +1,72 @@
+package id3
+
+import (
+	"errors"
+	"fmt"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/htmltext"
+)
+
+// FromProgram returns a Tag populated from the station and program meta-info.
+func FromProgram(station radiko.Station, prog radiko.Prog) (*Tag, error) {
+	start, err := prog.StartTime()
+	if err != nil {
+		return nil, err
+	}
+	dur, err := prog.Duration()
+	if err != nil {
+		return nil, err
+	}
+
+	desc := htmltext.PlainText(prog.Info)
+	if desc == "" {
+		desc = htmltext.PlainText(prog.Desc)
+	}
+
+	return &Tag{
+		Title:       prog.Title,
+		SubTitle:    prog.SubTitle,
+		Performers:  prog.Pfm,
+		Station:     station.Name,
+		Date:        start,
+		Length:      dur,
+		URL:         prog.URL,
+		Description: desc,
+	}, nil
+}
+
+// FromPrograms returns a Tag for a recording which spans the given programs.
+// The tag is populated from the first program,
+// and has a chapter for each program if there are more than one.
+func FromPrograms(station radiko.Station, progs []radiko.Prog) (*Tag, error) {
+	if len(progs) == 0 {
+		return nil, errors.New("programs are empty")
+	}
+
+	tag, err := FromProgram(station, progs[0])
+	if err != nil || len(progs) == 1 {
+		return tag, err
+	}
+
+	origin := tag.Date
+	for i, p := range progs {
+		start, err := p.StartTime()
+		if err != nil {
+			return nil, err
+		}
+		end, err := p.EndTime()
+		if err != nil {
+			return nil, err
+		}
+		tag.Chapters = append(tag.Chapters, Chapter{
+			ID:    fmt.Sprintf("chp%d", i),
+			Title: p.Title,
+			Start: start.Sub(origin),
+			End:   end.Sub(origin),
+		})
+	}
+	last := tag.Chapters[len(tag.Chapters)-1]
+	tag.Length = last.End
+	return tag, nil
+}
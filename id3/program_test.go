@@ -0,0 +1,71 @@
+package id3
+
+import (
+	"testing"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+var testStation = radiko.Station{ID: "LFR", Name: "ニッポン放送"}
+
+var testProgs = []radiko.Prog{
+	{
+		Ft:    "20161112230000",
+		To:    "20161112233000",
+		Dur:   "1800",
+		Title: "中居正広のSome girl’ SMAP",
+		Pfm:   "中居正広（ＳＭＡＰ）",
+		Info:  `twitterハッシュタグは「<a href="http://twitter.com/#!/search/%23jolf">#jolf</a>」<br>facebook`,
+	},
+	{
+		Ft:    "20161112233000",
+		To:    "20161113010000",
+		Dur:   "5400",
+		Title: "オールナイトニッポンサタデースペシャル 大倉くんと高橋くん",
+	},
+}
+
+func TestFromProgram(t *testing.T) {
+	tag, err := FromProgram(testStation, testProgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tag.Station != testStation.Name {
+		t.Errorf("expected %s, but %s", testStation.Name, tag.Station)
+	}
+	if expected := "twitterハッシュタグは「#jolf」\nfacebook"; tag.Description != expected {
+		t.Errorf("expected %q, but %q", expected, tag.Description)
+	}
+	if expected := 30 * time.Minute; tag.Length != expected {
+		t.Errorf("expected %s, but %s", expected, tag.Length)
+	}
+	if len(tag.Chapters) != 0 {
+		t.Errorf("unexpected chapters: %v", tag.Chapters)
+	}
+}
+
+func TestFromPrograms(t *testing.T) {
+	tag, err := FromPrograms(testStation, testProgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tag.Chapters) != len(testProgs) {
+		t.Fatalf("expected %d chapters, but %d", len(testProgs), len(tag.Chapters))
+	}
+	c := tag.Chapters[1]
+	if c.Start != 30*time.Minute || c.End != 2*time.Hour {
+		t.Errorf("unexpected chapter: %v", c)
+	}
+	if expected := 2 * time.Hour; tag.Length != expected {
+		t.Errorf("expected %s, but %s", expected, tag.Length)
+	}
+}
+
+func TestFromPrograms_Empty(t *testing.T) {
+	if _, err := FromPrograms(testStation, nil); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
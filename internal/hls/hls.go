@@ -0,0 +1,275 @@
+// Package hls downloads HLS audio segments served by radiko.
+package hls
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/grafov/m3u8"
+	radiko "github.com/yyoshiki41/go-radiko"
+)
+
+const (
+	defaultPollInterval = 5 * time.Second
+	// defaultMaxStalls is the number of consecutive polls without new segments
+	// after which a playlist is considered finished.
+	defaultMaxStalls = 6
+)
+
+// ErrStalled is returned when a playlist stops growing before it ends.
+var ErrStalled = errors.New("hls: playlist stalled")
+
+// StatusError is returned when a server responds with a non-2xx status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("hls: failed to get %s: status=%d", e.URL, e.StatusCode)
+}
+
+// Doer is the interface that wraps Do method.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Fetcher fetches playlists and segments.
+//
+// A Fetcher remembers the segments it has copied from the window of a playlist,
+// so that Copy again after a reconnect resumes without duplicating them.
+// Use a new Fetcher for another stream, and do not call Copy concurrently.
+type Fetcher struct {
+	Client Doer
+	// Header is added to every request.
+	Header http.Header
+	// Tokens, if set, provides the auth_token sent with every request.
+	// A request rejected with 401 or 403 is retried once with a refreshed token,
+	// so that a long stream survives the expiry of the token.
+	Tokens *radiko.TokenManager
+	// PollInterval is the interval of reloading a live playlist.
+	PollInterval time.Duration
+	// MaxStalls is the number of consecutive reloads without new segments
+	// after which Copy gives up.
+	MaxStalls int
+
+	// seen is the URIs of the copied segments in the last playlist.
+	seen map[string]bool
+}
+
+// NewFetcher returns a Fetcher which accesses radiko with the managed client
+// and its auth_token.
+func NewFetcher(tokens *radiko.TokenManager) *Fetcher {
+	return &Fetcher{
+		Client: tokens.Client(),
+		Tokens: tokens,
+	}
+}
+
+// Options controls Copy.
+type Options struct {
+	// Duration stops copying after segments of the given length are written.
+	// Zero means until the end of the playlist.
+	Duration time.Duration
+}
+
+// Copy writes the segments of the playlist at uri to w.
+// A master playlist is resolved to its first variant.
+// Live playlists are reloaded until the playlist ends,
+// the requested duration is written, or ctx is done.
+func (f *Fetcher) Copy(ctx context.Context, w io.Writer, uri string, opts Options) error {
+	mediaURL, err := f.MediaPlaylistURL(ctx, uri)
+	if err != nil {
+		return err
+	}
+
+	var written time.Duration
+	stalls := 0
+	for {
+		p, err := f.mediaPlaylist(ctx, mediaURL)
+		if err != nil {
+			return err
+		}
+
+		// Segments which have left the window never come back,
+		// so only the segments of this playlist are remembered.
+		seen := f.seen
+		f.seen = make(map[string]bool, len(p.Segments))
+		fresh := 0
+		for _, seg := range p.Segments {
+			if seg == nil {
+				continue
+			}
+			if seen[seg.URI] {
+				f.seen[seg.URI] = true
+				continue
+			}
+			fresh++
+
+			segURL, err := Resolve(mediaURL, seg.URI)
+			if err != nil {
+				return err
+			}
+			if err := f.copySegment(ctx, w, segURL); err != nil {
+				return err
+			}
+			f.seen[seg.URI] = true
+			written += time.Duration(seg.Duration * float64(time.Second))
+			if opts.Duration > 0 && written >= opts.Duration {
+				return nil
+			}
+		}
+		if p.Closed {
+			return nil
+		}
+
+		if fresh == 0 {
+			stalls++
+			if stalls >= f.maxStalls() {
+				return ErrStalled
+			}
+		} else {
+			stalls = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(f.pollInterval(p)):
+		}
+	}
+}
+
+// MediaPlaylistURL returns the url of the media playlist.
+// If uri is a master playlist, the url of its first variant is returned.
+func (f *Fetcher) MediaPlaylistURL(ctx context.Context, uri string) (string, error) {
+	p, listType, err := f.playlist(ctx, uri)
+	if err != nil {
+		return "", err
+	}
+	if listType == m3u8.MEDIA {
+		return uri, nil
+	}
+
+	master := p.(*m3u8.MasterPlaylist)
+	if len(master.Variants) == 0 || master.Variants[0] == nil {
+		return "", errors.New("hls: no variant in master playlist")
+	}
+	return Resolve(uri, master.Variants[0].URI)
+}
+
+// Get returns the response of GET request to uri.
+// The caller must close the response body.
+func (f *Fetcher) Get(ctx context.Context, uri string) (*http.Response, error) {
+	resp, token, err := f.get(ctx, uri)
+	var serr *StatusError
+	if f.Tokens != nil && errors.As(err, &serr) &&
+		(serr.StatusCode == http.StatusUnauthorized || serr.StatusCode == http.StatusForbidden) {
+		if err := f.Tokens.RefreshRejected(ctx, token); err != nil {
+			return nil, err
+		}
+		resp, _, err = f.get(ctx, uri)
+	}
+	return resp, err
+}
+
+// get sends GET request to uri, and returns the response and the auth_token sent.
+func (f *Fetcher) get(ctx context.Context, uri string) (*http.Response, string, error) {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range f.Header {
+		req.Header[k] = v
+	}
+	var token string
+	if f.Tokens != nil {
+		token = f.Tokens.Client().AuthToken()
+		req.Header.Set("X-Radiko-AuthToken", token)
+	}
+
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return nil, token, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, token, &StatusError{URL: uri, StatusCode: resp.StatusCode}
+	}
+	return resp, token, nil
+}
+
+func (f *Fetcher) playlist(ctx context.Context, uri string) (m3u8.Playlist, m3u8.ListType, error) {
+	resp, err := f.Get(ctx, uri)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	return m3u8.DecodeFrom(resp.Body, false)
+}
+
+func (f *Fetcher) mediaPlaylist(ctx context.Context, uri string) (*m3u8.MediaPlaylist, error) {
+	p, listType, err := f.playlist(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+	if listType != m3u8.MEDIA {
+		return nil, errors.New("hls: not a media playlist: " + uri)
+	}
+	return p.(*m3u8.MediaPlaylist), nil
+}
+
+func (f *Fetcher) copySegment(ctx context.Context, w io.Writer, uri string) error {
+	resp, err := f.Get(ctx, uri)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func (f *Fetcher) client() Doer {
+	if f.Client == nil {
+		return http.DefaultClient
+	}
+	return f.Client
+}
+
+func (f *Fetcher) pollInterval(p *m3u8.MediaPlaylist) time.Duration {
+	if f.PollInterval > 0 {
+		return f.PollInterval
+	}
+	if p.TargetDuration > 0 {
+		return time.Duration(p.TargetDuration * float64(time.Second))
+	}
+	return defaultPollInterval
+}
+
+func (f *Fetcher) maxStalls() int {
+	if f.MaxStalls > 0 {
+		return f.MaxStalls
+	}
+	return defaultMaxStalls
+}
+
+// Resolve resolves a uri in a playlist against the playlist url.
+func Resolve(base, ref string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(r).String(), nil
+}
@@ -0,0 +1,111 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newTestServer(closed bool) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/master.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-STREAM-INF:BANDWIDTH=52973\nmedia.m3u8\n")
+	})
+	mux.HandleFunc("/media.m3u8", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "1" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:1\n")
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "#EXTINF:5,\nseg/%d.aac\n", i)
+		}
+		if closed {
+			fmt.Fprint(w, "#EXT-X-ENDLIST\n")
+		}
+	})
+	mux.HandleFunc("/seg/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path[len("/seg/"):len("/seg/")+1])
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTestFetcher() *Fetcher {
+	return &Fetcher{
+		Header:       http.Header{"X-Test": {"1"}},
+		PollInterval: time.Millisecond,
+		MaxStalls:    2,
+	}
+}
+
+func TestCopy(t *testing.T) {
+	ts := newTestServer(true)
+	defer ts.Close()
+
+	var b bytes.Buffer
+	if err := newTestFetcher().Copy(context.Background(), &b, ts.URL+"/master.m3u8", Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "012"; b.String() != expected {
+		t.Errorf("expected %s, but %s", expected, b.String())
+	}
+}
+
+func TestCopy_Duration(t *testing.T) {
+	ts := newTestServer(false)
+	defer ts.Close()
+
+	var b bytes.Buffer
+	opts := Options{Duration: 10 * time.Second}
+	if err := newTestFetcher().Copy(context.Background(), &b, ts.URL+"/media.m3u8", opts); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "01"; b.String() != expected {
+		t.Errorf("expected %s, but %s", expected, b.String())
+	}
+}
+
+func TestCopy_Stalled(t *testing.T) {
+	ts := newTestServer(false)
+	defer ts.Close()
+
+	var b bytes.Buffer
+	err := newTestFetcher().Copy(context.Background(), &b, ts.URL+"/media.m3u8", Options{})
+	if err != ErrStalled {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCopy_StatusError(t *testing.T) {
+	ts := newTestServer(true)
+	defer ts.Close()
+
+	f := newTestFetcher()
+	f.Header = nil
+	var b bytes.Buffer
+	if err := f.Copy(context.Background(), &b, ts.URL+"/media.m3u8", Options{}); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestCopy_Resume(t *testing.T) {
+	ts := newTestServer(false)
+	defer ts.Close()
+
+	f := newTestFetcher()
+	var b bytes.Buffer
+	if err := f.Copy(context.Background(), &b, ts.URL+"/media.m3u8", Options{Duration: 10 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+	// The segments in the window are not copied again.
+	if err := f.Copy(context.Background(), &b, ts.URL+"/media.m3u8", Options{}); err != ErrStalled {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expected := "012"; b.String() != expected {
+		t.Errorf("expected %s, but %s", expected, b.String())
+	}
+}
@@ -0,0 +1,249 @@
+// Package radikotest provides a fake radiko.jp server for tests.
+package radikotest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AuthToken is the prefix of auth_tokens issued by the fake server.
+	AuthToken = "test-auth-token"
+	// AreaID is the area detected by the fake server.
+	AreaID = "JP13"
+	// Auth2Response is the body of auth2.
+	Auth2Response = "JP13,東京都,tokyo Japan"
+
+	// LiveSegments is the number of segments in a live media playlist.
+	LiveSegments = 3
+	// TimeshiftSegments is the number of segments in a timeshift media playlist.
+	TimeshiftSegments = 3
+)
+
+// Server is a fake radiko.jp server.
+// Use Transport to route requests for any host to the server.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	counts     map[string]int
+	liveSeqs   map[string]int
+	failures   map[string]int
+	generation int
+	now        time.Time
+}
+
+// NewServer starts and returns a new Server.
+func NewServer() *Server {
+	s := &Server{
+		counts:   map[string]int{},
+		liveSeqs: map[string]int{},
+		failures: map[string]int{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Transport returns an http.RoundTripper which sends every request to the server.
+func (s *Server) Transport() http.RoundTripper {
+	u, _ := url.Parse(s.URL)
+	return &rewriteTransport{target: u}
+}
+
+// HTTPClient returns an http.Client which sends every request to the server.
+func (s *Server) HTTPClient() *http.Client {
+	return &http.Client{Transport: s.Transport()}
+}
+
+// RevokeTokens rejects the auth_tokens issued so far.
+func (s *Server) RevokeTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generation++
+}
+
+func (s *Server) authToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("%s-%d", AuthToken, s.generation)
+}
+
+// SetNow sets the server time (<srvtime>) of the program guides,
+// which is 2016-11-12 23:23:47 JST of the testdata by default.
+func (s *Server) SetNow(t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = t
+}
+
+// FailNext responds 503 Service Unavailable to the next n requests
+// whose path has the given prefix.
+func (s *Server) FailNext(prefix string, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[prefix] += n
+}
+
+// fail reports whether the request of the path should fail, consuming a failure.
+func (s *Server) fail(p string) bool {
+	for prefix, n := range s.failures {
+		if n > 0 && strings.HasPrefix(p, prefix) {
+			s.failures[prefix]--
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of requests whose path has the given prefix.
+func (s *Server) Count(prefix string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for p, c := range s.counts {
+		if strings.HasPrefix(p, prefix) {
+			n += c
+		}
+	}
+	return n
+}
+
+var srvtimePattern = regexp.MustCompile(`<srvtime>\d+</srvtime>`)
+
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	r.Host = req.URL.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Path
+	s.mu.Lock()
+	s.counts[p]++
+	fail := s.fail(p)
+	s.mu.Unlock()
+	if fail {
+		http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch {
+	case p == "/area":
+		fmt.Fprintf(w, `document.write('<span class="%s">TOKYO JAPAN</span>');`, AreaID)
+	case p == "/v2/api/auth1":
+		w.Header().Set("X-Radiko-AuthToken", s.authToken())
+		w.Header().Set("X-Radiko-KeyLength", "16")
+		w.Header().Set("X-Radiko-KeyOffset", "0")
+		w.WriteHeader(http.StatusOK)
+	case p == "/v2/api/auth2":
+		if r.Header.Get("X-Radiko-AuthToken") != s.authToken() || r.Header.Get("X-Radiko-Partialkey") == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, Auth2Response)
+	case p == "/v2/api/program/now",
+		strings.HasPrefix(p, "/v3/program/date/"),
+		strings.HasPrefix(p, "/v3/program/station/weekly/"):
+		s.serveGuide(w, r)
+	case strings.HasPrefix(p, "/v2/station/stream_smh_multi/"):
+		id := strings.TrimSuffix(filepath.Base(p), ".xml")
+		fmt.Fprintf(w, `<urls><url areafree="0"><playlist_create_url>https://radiko.jp/v2/api/playlist_create/%s</playlist_create_url></url></urls>`, id)
+	case strings.HasPrefix(p, "/v2/api/playlist_create/"):
+		if !s.authorized(w, r) {
+			return
+		}
+		fmt.Fprintf(w, "https://radiko.jp/live/%s/playlist.m3u8\n", filepath.Base(p))
+	case strings.HasPrefix(p, "/v3/station/stream/pc_html5/"):
+		fmt.Fprint(w, `<urls><url timefree="1" areafree="0"><playlist_create_url>https://radiko.jp/tf/playlist.m3u8</playlist_create_url></url></urls>`)
+	case p == "/tf/playlist.m3u8":
+		if !s.authorized(w, r) {
+			return
+		}
+		fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:BANDWIDTH=52973\nhttps://radiko.jp/tf/medialist?%s\n", r.URL.RawQuery)
+	case p == "/tf/medialist":
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:0\n")
+		for i := 0; i < TimeshiftSegments; i++ {
+			fmt.Fprintf(w, "#EXTINF:5,\nhttps://radiko.jp/tf/segments/%d.aac\n", i)
+		}
+		fmt.Fprint(w, "#EXT-X-ENDLIST\n")
+	case strings.HasPrefix(p, "/live/") && strings.HasSuffix(p, "/playlist.m3u8"):
+		fmt.Fprint(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-STREAM-INF:BANDWIDTH=52973\nchunklist.m3u8\n")
+	case strings.HasPrefix(p, "/live/") && strings.HasSuffix(p, "/chunklist.m3u8"):
+		s.serveLiveChunklist(w, filepath.Base(filepath.Dir(p)))
+	case strings.HasPrefix(p, "/live/"), strings.HasPrefix(p, "/tf/segments/"):
+		if !s.authorized(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "audio/aac")
+		fmt.Fprint(w, Segment(p))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveGuide serves the program guide of the testdata at the server time.
+func (s *Server) serveGuide(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	now := s.now
+	s.mu.Unlock()
+	path := filepath.Join(testdataDir(), "stations.xml")
+	if now.IsZero() {
+		http.ServeFile(w, r, path)
+		return
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.Write(srvtimePattern.ReplaceAll(b, []byte(fmt.Sprintf("<srvtime>%d</srvtime>", now.Unix()))))
+}
+
+// serveLiveChunklist serves a sliding window which advances by a segment per request.
+func (s *Server) serveLiveChunklist(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	seq := s.liveSeqs[id]
+	s.liveSeqs[id]++
+	s.mu.Unlock()
+
+	fmt.Fprintf(w, "#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:5\n#EXT-X-MEDIA-SEQUENCE:%d\n", seq)
+	for i := seq; i < seq+LiveSegments; i++ {
+		fmt.Fprintf(w, "#EXTINF:5,\nsegments/%d.aac\n", i)
+	}
+}
+
+// Segment returns the body of the segment at path.
+func Segment(path string) string {
+	return "[" + path + "]"
+}
+
+func (s *Server) authorized(w http.ResponseWriter, r *http.Request) bool {
+	if r.Header.Get("X-Radiko-AuthToken") != s.authToken() {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func testdataDir() string {
+	_, currentFile, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(currentFile), "..", "..", "testdata")
+}
@@ -46,3 +46,14 @@ func ProgramsDate(t time.Time) string {
 	}
 	return localTime.Format(dateLayout)
 }
+
+// ParseDatetime parses a textual representation formatted in datetimeLayout
+// and returns the time value in Asia/Tokyo timezone.
+func ParseDatetime(s string) (time.Time, error) {
+	return time.ParseInLocation(datetimeLayout, s, location)
+}
+
+// Location returns the Asia/Tokyo timezone used by radiko.
+func Location() *time.Location {
+	return location
+}
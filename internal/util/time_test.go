@@ -40,3 +40,17 @@ func TestProgramsDate(t *testing.T) {
 		t.Errorf("expected %s, but %s", expected, pDate)
 	}
 }
+
+func TestParseDatetime(t *testing.T) {
+	const s = "20161112220000"
+	d, err := ParseDatetime(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := Datetime(d); s != actual {
+		t.Errorf("expected %s, but %s", s, actual)
+	}
+	if _, err := ParseDatetime("2016"); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
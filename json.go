@@ -0,0 +1,141 @@
+package radiko
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/yyoshiki41/go-radiko/internal/util"
+)
+
+// MarshalJSON implements the json.Marshaler interface.
+//
+// The schema is stable across releases:
+//
+//	{
+//	  "id":       "LFR",           // station ID
+//	  "name":     "ニッポン放送",  // station name
+//	  "programs": [...]            // programs encoded by Prog.MarshalJSON
+//	}
+func (s Station) MarshalJSON() ([]byte, error) {
+	progs := s.Programs()
+	if progs == nil {
+		progs = []Prog{}
+	}
+	return json.Marshal(stationJSON{
+		ID:       s.ID,
+		Name:     s.Name,
+		Programs: progs,
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It decodes the schema encoded by MarshalJSON.
+func (s *Station) UnmarshalJSON(b []byte) error {
+	var v stationJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*s = Station{
+		ID:    v.ID,
+		Name:  v.Name,
+		Progs: Progs{Progs: v.Programs},
+	}
+	return nil
+}
+
+type stationJSON struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Programs []Prog `json:"programs"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+//
+// The schema is stable across releases:
+//
+//	{
+//	  "start":       "2016-11-12T23:00:00+09:00", // RFC 3339 in JST, omitted if unknown
+//	  "end":         "2016-11-12T23:30:00+09:00", // RFC 3339 in JST, omitted if unknown
+//	  "duration":    1800,                        // seconds
+//	  "title":       "...",
+//	  "sub_title":   "...",
+//	  "performers":  "...",
+//	  "description": "...",                       // raw HTML as provided by radiko
+//	  "info":        "...",                       // raw HTML as provided by radiko
+//	  "url":         "..."
+//	}
+func (p Prog) MarshalJSON() ([]byte, error) {
+	v := progJSON{
+		Title:       p.Title,
+		SubTitle:    p.SubTitle,
+		Performers:  p.Pfm,
+		Description: p.Desc,
+		Info:        p.Info,
+		URL:         p.URL,
+	}
+
+	if p.Ft != "" {
+		start, err := p.StartTime()
+		if err != nil {
+			return nil, err
+		}
+		v.Start = &start
+	}
+	if p.To != "" {
+		end, err := p.EndTime()
+		if err != nil {
+			return nil, err
+		}
+		v.End = &end
+	}
+	if p.Dur != "" {
+		d, err := p.Duration()
+		if err != nil {
+			return nil, err
+		}
+		v.Duration = int64(d / time.Second)
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It decodes the schema encoded by MarshalJSON.
+// Ftl and Tol are not part of the schema and are left empty.
+func (p *Prog) UnmarshalJSON(b []byte) error {
+	var v progJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	*p = Prog{
+		Title:    v.Title,
+		SubTitle: v.SubTitle,
+		Pfm:      v.Performers,
+		Desc:     v.Description,
+		Info:     v.Info,
+		URL:      v.URL,
+	}
+
+	if v.Start != nil {
+		p.Ft = util.Datetime(*v.Start)
+	}
+	if v.End != nil {
+		p.To = util.Datetime(*v.End)
+	}
+	if v.Duration != 0 {
+		p.Dur = strconv.FormatInt(v.Duration, 10)
+	}
+	return nil
+}
+
+type progJSON struct {
+	Start       *time.Time `json:"start,omitempty"`
+	End         *time.Time `json:"end,omitempty"`
+	Duration    int64      `json:"duration"`
+	Title       string     `json:"title"`
+	SubTitle    string     `json:"sub_title"`
+	Performers  string     `json:"performers"`
+	Description string     `json:"description"`
+	Info        string     `json:"info"`
+	URL         string     `json:"url"`
+}
@@ -0,0 +1,130 @@
+package radiko
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func loadTestStations(t *testing.T) Stations {
+	return loadTestStationsData(t).stations()
+}
+
+func loadTestStationsData(t *testing.T) *stationsData {
+	file, err := os.Open(filepath.Join(testdataDir, "stations.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var d stationsData
+	if err = decodeStationsData(file, &d); err != nil {
+		t.Fatal(err)
+	}
+	return &d
+}
+
+func TestStations_MarshalJSON(t *testing.T) {
+	b, err := json.Marshal(loadTestStations(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var v []struct {
+		ID       string `json:"id"`
+		Programs []struct {
+			Start    string `json:"start"`
+			Duration int    `json:"duration"`
+			Title    string `json:"title"`
+		} `json:"programs"`
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v) != 2 || v[1].ID != "LFR" || len(v[1].Programs) != 2 {
+		t.Fatalf("unexpected json: %s", b)
+	}
+	p := v[1].Programs[0]
+	if expected := "2016-11-12T23:00:00+09:00"; p.Start != expected {
+		t.Errorf("expected %s, but %s", expected, p.Start)
+	}
+	if expected := 1800; p.Duration != expected {
+		t.Errorf("expected %d, but %d", expected, p.Duration)
+	}
+}
+
+func TestProg_MarshalJSON_Empty(t *testing.T) {
+	b, err := json.Marshal(Prog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := v["start"]; ok {
+		t.Errorf("start should be omitted: %s", b)
+	}
+}
+
+func TestProg_MarshalJSON_InvalidTime(t *testing.T) {
+	if _, err := json.Marshal(Prog{Ft: "invalid"}); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestStations_UnmarshalJSON(t *testing.T) {
+	stations := loadTestStations(t)
+	b, err := json.Marshal(stations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Stations
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(stations) {
+		t.Fatalf("expected %d, but %d", len(stations), len(got))
+	}
+	for i, s := range stations {
+		if got[i].ID != s.ID || got[i].Name != s.Name {
+			t.Errorf("expected %s %s, but %s %s", s.ID, s.Name, got[i].ID, got[i].Name)
+		}
+		progs, gotProgs := s.Programs(), got[i].Programs()
+		if len(gotProgs) != len(progs) {
+			t.Fatalf("expected %d, but %d", len(progs), len(gotProgs))
+		}
+		for j, p := range progs {
+			g := gotProgs[j]
+			if g.Ft != p.Ft || g.To != p.To || g.Dur != p.Dur {
+				t.Errorf("expected %s-%s (%s), but %s-%s (%s)", p.Ft, p.To, p.Dur, g.Ft, g.To, g.Dur)
+			}
+			if g.Title != p.Title || g.Pfm != p.Pfm || g.Desc != p.Desc || g.Info != p.Info || g.URL != p.URL {
+				t.Errorf("expected %+v, but %+v", p, g)
+			}
+		}
+	}
+
+	b2, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b2) != string(b) {
+		t.Errorf("expected %s, but %s", b, b2)
+	}
+}
+
+func TestProg_UnmarshalJSON_Empty(t *testing.T) {
+	var p Prog
+	if err := json.Unmarshal([]byte(`{"title":"test"}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Ft != "" || p.To != "" || p.Dur != "" {
+		t.Errorf("expected empty times, but %+v", p)
+	}
+	if p.Title != "test" {
+		t.Errorf("expected test, but %s", p.Title)
+	}
+}
@@ -0,0 +1,121 @@
+package radiko
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+)
+
+const livePlaylistCreatePath = "/v2/api/playlist_create/"
+
+// AreafreePolicy selects stream urls by their areafree attribute.
+type AreafreePolicy int
+
+const (
+	// PreferAreaLocked prefers area-locked urls and falls back to area-free urls.
+	PreferAreaLocked AreafreePolicy = iota
+	// PreferAreafree prefers area-free urls and falls back to area-locked urls.
+	PreferAreafree
+	// AreaLockedOnly uses area-locked urls only.
+	AreaLockedOnly
+	// AreafreeOnly uses area-free urls only. It requires the premium member.
+	AreafreeOnly
+)
+
+// ErrStreamURLNotFound is returned when no stream url matches the AreafreePolicy.
+var ErrStreamURLNotFound = errors.New("stream url not found")
+
+// AreafreePolicy returns the policy to select stream urls.
+func (c *Client) AreafreePolicy() AreafreePolicy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.areafreePolicy
+}
+
+// SetAreafreePolicy sets the policy to select stream urls.
+func (c *Client) SetAreafreePolicy(p AreafreePolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.areafreePolicy = p
+}
+
+// selectURL returns the first candidate which matches the policy.
+func (p AreafreePolicy) selectURL(n int, areafree func(i int) bool) (int, bool) {
+	first, second := false, true
+	switch p {
+	case PreferAreafree:
+		first, second = true, false
+	case AreaLockedOnly:
+		first, second = false, false
+	case AreafreeOnly:
+		first, second = true, true
+	}
+
+	for _, want := range []bool{first, second} {
+		for i := 0; i < n; i++ {
+			if areafree(i) == want {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// LivePlaylistM3U8 returns the m3u8 url of the live stream.
+func (c *Client) LivePlaylistM3U8(ctx context.Context, stationID string) (string, error) {
+	if ctx == nil {
+		return "", errors.New("Context is nil")
+	}
+	if stationID == "" {
+		return "", errors.New("StationID is empty")
+	}
+
+	items, err := c.GetStreamSmhMultiURL(ctx, stationID)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []SmhURLItem
+	for _, item := range items {
+		// playlist_create endpoint returns a playable m3u8 url.
+		if strings.Contains(item.PlaylistCreateURL, livePlaylistCreatePath) {
+			candidates = append(candidates, item)
+		}
+	}
+	i, ok := c.AreafreePolicy().selectURL(len(candidates), func(i int) bool {
+		return candidates[i].Areafree
+	})
+	if !ok {
+		return "", ErrStreamURLNotFound
+	}
+
+	return c.requestLivePlaylistURL(ctx, candidates[i].PlaylistCreateURL)
+}
+
+func (c *Client) requestLivePlaylistURL(ctx context.Context, playlistCreateURL string) (string, error) {
+	req, err := c.newStreamRequest(ctx, "GET", playlistCreateURL)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", &PlaylistStatusError{Op: "create live playlist", StatusCode: resp.StatusCode, Body: snippet(body)}
+	}
+
+	uri := strings.TrimSpace(string(body))
+	if uri == "" {
+		return "", errors.New("empty playlist url from playlist_create")
+	}
+	return uri, nil
+}
@@ -0,0 +1,94 @@
+package radiko
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newLiveTestServer(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	ts := httptest.NewServer(mux)
+	mux.HandleFunc("/v2/station/stream_smh_multi/LFR.xml", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<urls>
+  <url areafree="1"><playlist_create_url>%[1]s/v2/api/playlist_create/LFR?areafree=1</playlist_create_url></url>
+  <url areafree="0"><playlist_create_url>%[1]s/v2/api/playlist_create/LFR?areafree=0</playlist_create_url></url>
+  <url areafree="0"><playlist_create_url>%[1]s/other/LFR</playlist_create_url></url>
+</urls>`, ts.URL)
+	})
+	mux.HandleFunc("/v2/api/playlist_create/LFR", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(radikoAuthTokenHeader) != "token" || r.Header.Get(radikoAppHeader) == "" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		fmt.Fprintf(w, "\nhttps://example.com/%s.m3u8\n", r.URL.Query().Get("areafree"))
+	})
+	return ts
+}
+
+func TestLivePlaylistM3U8(t *testing.T) {
+	ts := newLiveTestServer(t)
+	defer ts.Close()
+
+	cases := []struct {
+		policy   AreafreePolicy
+		expected string
+	}{
+		{PreferAreaLocked, "https://example.com/0.m3u8"},
+		{PreferAreafree, "https://example.com/1.m3u8"},
+		{AreaLockedOnly, "https://example.com/0.m3u8"},
+		{AreafreeOnly, "https://example.com/1.m3u8"},
+	}
+	for _, c := range cases {
+		client := newTestClient(t, ts)
+		client.setAuthTokenHeader("token")
+		client.SetAreafreePolicy(c.policy)
+
+		uri, err := client.LivePlaylistM3U8(context.Background(), "LFR")
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if uri != c.expected {
+			t.Errorf("expected %s, but %s", c.expected, uri)
+		}
+	}
+}
+
+func TestLivePlaylistM3U8_Unauthorized(t *testing.T) {
+	ts := newLiveTestServer(t)
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	_, err := client.LivePlaylistM3U8(context.Background(), "LFR")
+	var e *PlaylistStatusError
+	if !errors.As(err, &e) {
+		t.Fatalf("expected *PlaylistStatusError, but %v", err)
+	}
+	if e.StatusCode != http.StatusForbidden {
+		t.Errorf("expected %d, but %d", http.StatusForbidden, e.StatusCode)
+	}
+}
+
+func TestLivePlaylistM3U8_EmptyStationID(t *testing.T) {
+	ts := newLiveTestServer(t)
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	if _, err := client.LivePlaylistM3U8(context.Background(), ""); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestAreafreePolicy_SelectURL(t *testing.T) {
+	lockedOnly := func(int) bool { return false }
+	if _, ok := AreafreeOnly.selectURL(2, lockedOnly); ok {
+		t.Error("Should not select an area-locked url.")
+	}
+	if i, ok := PreferAreafree.selectURL(2, lockedOnly); !ok || i != 0 {
+		t.Errorf("Should fall back to an area-locked url: %d", i)
+	}
+}
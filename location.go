@@ -0,0 +1,71 @@
+package radiko
+
+import "fmt"
+
+// Location is the coordinates of a device.
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// header returns the value of X-Radiko-Location.
+func (l Location) header() string {
+	return fmt.Sprintf("%f,%f,gps", l.Latitude, l.Longitude)
+}
+
+// areaLocations are the coordinates of the prefectural offices.
+var areaLocations = map[string]Location{
+	"JP1":  {43.064615, 141.346807}, // 北海道
+	"JP2":  {40.824308, 140.739998}, // 青森
+	"JP3":  {39.703619, 141.152684}, // 岩手
+	"JP4":  {38.268837, 140.872100}, // 宮城
+	"JP5":  {39.718614, 140.102364}, // 秋田
+	"JP6":  {38.240436, 140.363633}, // 山形
+	"JP7":  {37.750299, 140.467551}, // 福島
+	"JP8":  {36.341811, 140.446793}, // 茨城
+	"JP9":  {36.565725, 139.883565}, // 栃木
+	"JP10": {36.390668, 139.060406}, // 群馬
+	"JP11": {35.856999, 139.648849}, // 埼玉
+	"JP12": {35.605057, 140.123306}, // 千葉
+	"JP13": {35.689488, 139.691706}, // 東京
+	"JP14": {35.447507, 139.642345}, // 神奈川
+	"JP15": {37.902552, 139.023095}, // 新潟
+	"JP16": {36.695291, 137.211338}, // 富山
+	"JP17": {36.594682, 136.625573}, // 石川
+	"JP18": {36.065178, 136.221527}, // 福井
+	"JP19": {35.664158, 138.568449}, // 山梨
+	"JP20": {36.651299, 138.180956}, // 長野
+	"JP21": {35.391227, 136.722291}, // 岐阜
+	"JP22": {34.977120, 138.383084}, // 静岡
+	"JP23": {35.180188, 136.906565}, // 愛知
+	"JP24": {34.730283, 136.508588}, // 三重
+	"JP25": {35.004531, 135.868590}, // 滋賀
+	"JP26": {35.021247, 135.755597}, // 京都
+	"JP27": {34.686297, 135.519661}, // 大阪
+	"JP28": {34.691269, 135.183071}, // 兵庫
+	"JP29": {34.685334, 135.832742}, // 奈良
+	"JP30": {34.225987, 135.167509}, // 和歌山
+	"JP31": {35.503891, 134.237736}, // 鳥取
+	"JP32": {35.472295, 133.050500}, // 島根
+	"JP33": {34.661751, 133.934406}, // 岡山
+	"JP34": {34.396560, 132.459622}, // 広島
+	"JP35": {34.185956, 131.470649}, // 山口
+	"JP36": {34.065718, 134.559360}, // 徳島
+	"JP37": {34.340149, 134.043444}, // 香川
+	"JP38": {33.841624, 132.765681}, // 愛媛
+	"JP39": {33.559706, 133.531079}, // 高知
+	"JP40": {33.606576, 130.418297}, // 福岡
+	"JP41": {33.249442, 130.299794}, // 佐賀
+	"JP42": {32.744839, 129.873756}, // 長崎
+	"JP43": {32.789827, 130.741667}, // 熊本
+	"JP44": {33.238172, 131.612619}, // 大分
+	"JP45": {31.911096, 131.423893}, // 宮崎
+	"JP46": {31.560146, 130.557978}, // 鹿児島
+	"JP47": {26.212400, 127.680932}, // 沖縄
+}
+
+// AreaLocation returns the coordinates of the prefectural office of the area.
+func AreaLocation(areaID string) (Location, bool) {
+	l, ok := areaLocations[areaID]
+	return l, ok
+}
@@ -1,14 +1,32 @@
 package radiko
 
 import (
-	"net/http"
+	"context"
+	"net/url"
 
 	"github.com/yyoshiki41/go-radiko/internal/m3u8"
 )
 
 // GetChunklistFromM3U8 returns a slice of url.
+//
+// Deprecated: Use Client.GetChunklistFromM3U8, which supports context and custom HTTP clients.
 func GetChunklistFromM3U8(uri string) ([]string, error) {
-	resp, err := http.Get(uri)
+	return defaultClient().GetChunklistFromM3U8(context.Background(), uri)
+}
+
+// GetChunklistFromM3U8 returns a slice of url.
+func (c *Client) GetChunklistFromM3U8(ctx context.Context, uri string) ([]string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequestURL(ctx, "GET", u, &Params{setAuthToken: c.AuthToken() != ""})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,10 @@
 package radiko
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"testing"
 )
 
@@ -10,3 +14,24 @@ func TestGetChunklistFromM3U8(t *testing.T) {
 		t.Error("Should detect an error.")
 	}
 }
+
+func TestClient_GetChunklistFromM3U8(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(radikoAuthTokenHeader) != "token" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join(testdataDir, "chunklist.m3u8"))
+	}))
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	client.setAuthTokenHeader("token")
+	chunklist, err := client.GetChunklistFromM3U8(context.Background(), ts.URL+"/chunklist.m3u8?a=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunklist) == 0 {
+		t.Error("chunklist is empty.")
+	}
+}
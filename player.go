@@ -2,7 +2,10 @@ package radiko
 
 import (
 	"compress/zlib"
+	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
@@ -10,7 +13,7 @@ import (
 )
 
 const (
-	playerURL = "http://radiko.jp/apps/js/flash/myplayer-release.swf"
+	playerPath = "apps/js/flash/myplayer-release.swf"
 
 	// swfextract
 	targetID     = 12 // swfextract -b "12"
@@ -23,17 +26,24 @@ const (
 )
 
 // DownloadPlayer downloads a swf player file.
+//
+// Deprecated: Use Client.DownloadPlayer, which supports context and custom HTTP clients.
 func DownloadPlayer(path string) error {
-	f, err := os.Create(path)
+	return defaultClient().DownloadPlayer(context.Background(), path)
+}
+
+// DownloadPlayer downloads a swf player file.
+func (c *Client) DownloadPlayer(ctx context.Context, path string) error {
+	resp, err := c.getPlayer(ctx)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
-	resp, err := http.Get(playerURL)
+	f, err := os.Create(path)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
 	_, err = io.Copy(f, resp.Body)
 	if closeErr := f.Close(); err == nil {
@@ -42,8 +52,29 @@ func DownloadPlayer(path string) error {
 	return err
 }
 
+func (c *Client) getPlayer(ctx context.Context) (*http.Response, error) {
+	req, err := c.newRequest(ctx, "GET", playerPath, &Params{})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to download player: status=%d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
 func downloadBinary() ([]byte, error) {
-	resp, err := http.Get(playerURL)
+	return defaultClient().downloadBinary(context.Background())
+}
+
+func (c *Client) downloadBinary(ctx context.Context) ([]byte, error) {
+	resp, err := c.getPlayer(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -52,20 +83,44 @@ func downloadBinary() ([]byte, error) {
 	return swfExtract(resp.Body)
 }
 
+// errMalformedSWF is returned when the swf player is truncated or broken.
+var errMalformedSWF = errors.New("swf extract failed: malformed swf")
+
 func swfExtract(body io.Reader) ([]byte, error) {
-	io.CopyN(ioutil.Discard, body, headerCWS)
-	zf, err := zlib.NewReader(body)
-	if err != nil {
-		return nil, err
+	header := make([]byte, headerCWS)
+	if _, err := io.ReadFull(body, header); err != nil {
+		return nil, errMalformedSWF
 	}
-	buf, err := ioutil.ReadAll(zf)
+
+	var r io.Reader
+	switch string(header[:3]) {
+	case "CWS":
+		zf, err := zlib.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer zf.Close()
+		r = zf
+	case "FWS":
+		r = body
+	default:
+		return nil, errMalformedSWF
+	}
+	buf, err := ioutil.ReadAll(r)
 	if err != nil {
 		return nil, err
 	}
+	return extractBinaryData(buf)
+}
 
+// extractBinaryData returns the binary data of targetID in the uncompressed swf body.
+func extractBinaryData(buf []byte) ([]byte, error) {
 	offset := 0
 
 	// Skip Rect
+	if len(buf) == 0 {
+		return nil, errMalformedSWF
+	}
 	rectSize := int(buf[offset] >> 3)
 	rectOffset := (headerRect + rectNum*rectSize + 7) / 8
 
@@ -75,22 +130,25 @@ func swfExtract(body io.Reader) ([]byte, error) {
 	offset += headerRest
 
 	// Read tags
-	for i := 0; ; i++ {
+	for {
+		if offset+2 > len(buf) {
+			return nil, errMalformedSWF
+		}
 		// tag code
 		code := int(buf[offset+1])<<2 + int(buf[offset])>>6
 
 		// tag length
-		len := int(buf[offset] & 0x3f)
+		length := int(buf[offset] & 0x3f)
 
 		// Skip tag header
 		offset += 2
 
 		// tag length (if long version)
-		if len == 0x3f {
-			len = int(buf[offset])
-			len += int(buf[offset+1]) << 8
-			len += int(buf[offset+2]) << 16
-			len += int(buf[offset+3]) << 24
+		if length == 0x3f {
+			if offset+4 > len(buf) {
+				return nil, errMalformedSWF
+			}
+			length = int(binary.LittleEndian.Uint32(buf[offset:]))
 
 			// skip tag lentgh header
 			offset += 4
@@ -100,14 +158,19 @@ func swfExtract(body io.Reader) ([]byte, error) {
 		if code == 0 {
 			return nil, errors.New("swf extract failed")
 		}
-		// tag ID
-		id := int(buf[offset]) + int(buf[offset+1])<<8
+		if length < 0 || offset+length > len(buf) {
+			return nil, errMalformedSWF
+		}
 
 		// Found?
-		if code == targetCode && id == targetID {
-			return buf[offset+binaryOffset : offset+len], nil
+		if code == targetCode && length >= binaryOffset {
+			// tag ID
+			id := int(buf[offset]) + int(buf[offset+1])<<8
+			if id == targetID {
+				return buf[offset+binaryOffset : offset+length], nil
+			}
 		}
 
-		offset += len
+		offset += length
 	}
 }
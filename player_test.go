@@ -1,6 +1,15 @@
 package radiko
 
 import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path/filepath"
 	"testing"
 )
@@ -22,3 +31,101 @@ func TestDownloadBinary(t *testing.T) {
 		t.Skipf("Skipping test because player binary download is unavailable: %s", err)
 	}
 }
+
+func TestClient_DownloadPlayer_NotFound(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	dir, removeDir := createTestTempDir(t)
+	defer removeDir() // clean up
+
+	playerPath := filepath.Join(dir, "myplayer.swf")
+	err := newTestClient(t, ts).DownloadPlayer(context.Background(), playerPath)
+	if err == nil {
+		t.Error("Should detect an error.")
+	}
+	if _, err := os.Stat(playerPath); !os.IsNotExist(err) {
+		t.Errorf("Should not create a file: %v", err)
+	}
+}
+
+// testSWF returns a compressed swf which has the binary data of the id.
+func testSWF(t *testing.T, id uint16, data []byte) []byte {
+	var body bytes.Buffer
+	body.WriteByte(0)                   // Rect of nbits=0
+	body.Write([]byte{0, 0, 0, 0})      // frame rate and count
+	body.Write([]byte{0x7f, 0x00})      // ShowFrame tag with length 63 (long form)
+	body.Write([]byte{0, 0, 0, 0})      // length 0
+	tag := uint16(targetCode<<6 | 0x3f) // DefineBinaryData (long form)
+	binary.Write(&body, binary.LittleEndian, tag)
+	binary.Write(&body, binary.LittleEndian, uint32(binaryOffset+len(data)))
+	binary.Write(&body, binary.LittleEndian, id)
+	body.Write([]byte{0, 0, 0, 0}) // reserved
+	body.Write(data)
+	body.Write([]byte{0, 0}) // End tag
+
+	var swf bytes.Buffer
+	swf.WriteString("CWS")
+	swf.WriteByte(10)
+	binary.Write(&swf, binary.LittleEndian, uint32(8+body.Len()))
+	zw := zlib.NewWriter(&swf)
+	if _, err := zw.Write(body.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return swf.Bytes()
+}
+
+func TestSWFExtract(t *testing.T) {
+	key := []byte("test-auth-key")
+	b, err := swfExtract(bytes.NewReader(testSWF(t, targetID, key)))
+	if err != nil {
+		t.Fatalf("Failed to extract: %s", err)
+	}
+	if !bytes.Equal(b, key) {
+		t.Errorf("expected %s, but %s", key, b)
+	}
+
+	if _, err := swfExtract(bytes.NewReader(testSWF(t, targetID+1, key))); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestSWFExtract_Malformed(t *testing.T) {
+	// Truncated swfs must not panic.
+	var body bytes.Buffer
+	zw := zlib.NewWriter(&body)
+	zw.Write([]byte{0xff, 0, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	zw.Close()
+	inputs := [][]byte{
+		nil,
+		[]byte("CWS"),
+		[]byte("XWS\x0a\x00\x00\x00\x00"),
+		append([]byte("CWS\x0a\x00\x00\x00\x00"), body.Bytes()...),
+	}
+	full, err := ioutil.ReadAll(zlibReader(t, testSWF(t, targetID, []byte("key"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The binary data is complete without the End tag.
+	for i := 0; i < len(full)-2; i++ {
+		inputs = append(inputs, append([]byte("FWS\x0a\x00\x00\x00\x00"), full[:i]...))
+	}
+
+	for _, in := range inputs {
+		if _, err := swfExtract(bytes.NewReader(in)); err == nil {
+			t.Errorf("Should detect an error: %q", in)
+		}
+	}
+}
+
+// zlibReader returns the uncompressed body of the swf.
+func zlibReader(t *testing.T, swf []byte) io.Reader {
+	zr, err := zlib.NewReader(bytes.NewReader(swf[8:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
@@ -6,8 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"net/http"
 	"path"
+	"strconv"
 	"time"
 
 	"github.com/yyoshiki41/go-radiko/internal/util"
@@ -24,6 +25,15 @@ type Station struct {
 	Progs Progs  `xml:"progs,omitempty"`
 }
 
+// Programs returns the programs of the station.
+// Depending on the API, programs are nested in either <scd> or <progs>.
+func (s Station) Programs() []Prog {
+	if len(s.Progs.Progs) > 0 {
+		return s.Progs.Progs
+	}
+	return s.Scd.Progs.Progs
+}
+
 // Scd is a struct.
 type Scd struct {
 	Progs Progs `xml:"progs"`
@@ -50,32 +60,142 @@ type Prog struct {
 	URL      string `xml:"url"`
 }
 
+// StartTime returns the parsed start time (ft) of the program.
+func (p Prog) StartTime() (time.Time, error) {
+	return util.ParseDatetime(p.Ft)
+}
+
+// EndTime returns the parsed end time (to) of the program.
+func (p Prog) EndTime() (time.Time, error) {
+	return util.ParseDatetime(p.To)
+}
+
+// Duration returns the length of the program.
+func (p Prog) Duration() (time.Duration, error) {
+	sec, err := strconv.Atoi(p.Dur)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(sec) * time.Second, nil
+}
+
 // GetStations returns the program's meta-info.
 func (c *Client) GetStations(ctx context.Context, date time.Time) (Stations, error) {
+	req, key, err := c.stationsRequest(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := c.getGuide(req, key)
+	if err != nil {
+		return nil, err
+	}
+	return d.stations(), nil
+}
+
+// WalkPrograms calls fn for each program of the day in the area, in the order of the guide.
+// The guide is decoded incrementally, and walking stops when fn returns an error.
+// If fn returns ErrStopWalk, WalkPrograms returns nil.
+func (c *Client) WalkPrograms(ctx context.Context, date time.Time, fn func(Station, Prog) error) error {
+	req, key, err := c.stationsRequest(ctx, date)
+	if err != nil {
+		return err
+	}
+	return c.walkGuide(req, key, fn)
+}
+
+// stationsRequest returns the request for the programs of the day in the area,
+// and the key of the guide cache.
+func (c *Client) stationsRequest(ctx context.Context, date time.Time) (*http.Request, string, error) {
+	return c.areaStationsRequest(ctx, c.AreaID(), date)
+}
+
+// areaStationsRequest returns the request for the programs of the day in the given area,
+// and the key of the guide cache.
+func (c *Client) areaStationsRequest(ctx context.Context, areaID string, date time.Time) (*http.Request, string, error) {
+	programsDate := util.ProgramsDate(date)
 	apiEndpoint := path.Join(apiV3,
-		"program/date", util.ProgramsDate(date),
-		fmt.Sprintf("%s.xml", c.AreaID()))
+		"program/date", programsDate,
+		fmt.Sprintf("%s.xml", areaID))
 
 	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
+	if err != nil {
+		return nil, "", err
+	}
+	return req, path.Join("date", areaID, programsDate), nil
+}
+
+// GetAreaStations returns the program's meta-info of the day in the given area,
+// e.g. to find programs of other areas in timefree 30.
+func (c *Client) GetAreaStations(ctx context.Context, areaID string, date time.Time) (Stations, error) {
+	if areaID == "" {
+		return nil, errors.New("AreaID is empty")
+	}
+	req, key, err := c.areaStationsRequest(ctx, areaID, date)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := c.Do(req)
+	d, err := c.getGuide(req, key)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return d.stations(), nil
+}
 
-	var d stationsData
-	if err = decodeStationsData(resp.Body, &d); err != nil {
+// GetStationPrograms returns the programs of the day of the station,
+// which is available regardless of the area of the client.
+func (c *Client) GetStationPrograms(ctx context.Context, stationID string, date time.Time) (Stations, error) {
+	req, key, err := c.stationProgramsRequest(ctx, stationID, date)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := c.getGuide(req, key)
+	if err != nil {
 		return nil, err
 	}
 	return d.stations(), nil
 }
 
+// WalkStationPrograms calls fn for each program of the day of the station.
+// See WalkPrograms for the handling of errors returned by fn.
+func (c *Client) WalkStationPrograms(ctx context.Context, stationID string, date time.Time, fn func(Station, Prog) error) error {
+	req, key, err := c.stationProgramsRequest(ctx, stationID, date)
+	if err != nil {
+		return err
+	}
+	return c.walkGuide(req, key, fn)
+}
+
+// stationProgramsRequest returns the request for the programs of the day of the station,
+// and the key of the guide cache.
+func (c *Client) stationProgramsRequest(ctx context.Context, stationID string, date time.Time) (*http.Request, string, error) {
+	if stationID == "" {
+		return nil, "", errors.New("StationID is empty")
+	}
+	programsDate := util.ProgramsDate(date)
+	apiEndpoint := path.Join(apiV3,
+		"program/station/date", programsDate,
+		fmt.Sprintf("%s.xml", stationID))
+
+	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
+	if err != nil {
+		return nil, "", err
+	}
+	return req, path.Join("station", stationID, programsDate), nil
+}
+
 // GetNowPrograms returns the program's meta-info which are currently on the air.
 func (c *Client) GetNowPrograms(ctx context.Context) (Stations, error) {
+	d, err := c.getNowProgramsData(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return d.stations(), nil
+}
+
+func (c *Client) getNowProgramsData(ctx context.Context) (*stationsData, error) {
 	apiEndpoint := apiPath(apiV2, "program/now")
 
 	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{
@@ -87,42 +207,35 @@ func (c *Client) GetNowPrograms(ctx context.Context) (Stations, error) {
 		return nil, err
 	}
 
-	resp, err := c.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var d stationsData
-	if err = decodeStationsData(resp.Body, &d); err != nil {
-		return nil, err
-	}
-	return d.stations(), nil
+	return c.getStationsData(req)
 }
 
 // GetProgramByStartTime returns a specified program.
-// This API wraps GetStations.
+// This API wraps WalkPrograms, or the guide of the station in timefree 30
+// since the station may be in another area.
 func (c *Client) GetProgramByStartTime(ctx context.Context, stationID string, start time.Time) (*Prog, error) {
 	if stationID == "" {
 		return nil, errors.New("StationID is empty")
 	}
 
-	stations, err := c.GetStations(ctx, start)
-	if err != nil {
-		return nil, err
+	walk := c.WalkPrograms
+	if c.Timefree30() {
+		walk = func(ctx context.Context, date time.Time, fn func(Station, Prog) error) error {
+			return c.WalkStationPrograms(ctx, stationID, date, fn)
+		}
 	}
 
 	ft := util.Datetime(start)
 	var prog *Prog
-	for _, s := range stations {
-		if s.ID == stationID {
-			for _, p := range s.Progs.Progs {
-				if p.Ft == ft {
-					prog = &p
-					break
-				}
-			}
+	err := walk(ctx, start, func(s Station, p Prog) error {
+		if s.ID == stationID && p.Ft == ft {
+			prog = &p
+			return ErrStopWalk
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	if prog == nil {
 		return nil, ErrProgramNotFound
@@ -132,31 +245,140 @@ func (c *Client) GetProgramByStartTime(ctx context.Context, stationID string, st
 
 // GetWeeklyPrograms returns the weekly programs.
 func (c *Client) GetWeeklyPrograms(ctx context.Context, stationID string) (Stations, error) {
+	req, key, err := c.weeklyProgramsRequest(ctx, stationID)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := c.getGuide(req, key)
+	if err != nil {
+		return nil, err
+	}
+	return d.stations(), nil
+}
+
+// WalkWeeklyPrograms calls fn for each weekly program of the station, in the order of the guide.
+// See WalkPrograms for the handling of errors returned by fn.
+func (c *Client) WalkWeeklyPrograms(ctx context.Context, stationID string, fn func(Station, Prog) error) error {
+	req, key, err := c.weeklyProgramsRequest(ctx, stationID)
+	if err != nil {
+		return err
+	}
+	return c.walkGuide(req, key, fn)
+}
+
+// weeklyProgramsRequest returns the request for the weekly programs of the station,
+// and the key of the guide cache.
+func (c *Client) weeklyProgramsRequest(ctx context.Context, stationID string) (*http.Request, string, error) {
 	apiEndpoint := path.Join(apiV3,
 		"program/station/weekly",
 		fmt.Sprintf("%s.xml", stationID))
 
 	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	// The weekly programs are cached by the week of today's broadcast date.
+	today, err := time.Parse("20060102", util.ProgramsDate(c.Now()))
+	if err != nil {
+		return nil, "", err
+	}
+	year, week := today.ISOWeek()
+	return req, path.Join("weekly", stationID, fmt.Sprintf("%d-W%02d", year, week)), nil
+}
+
+// walkGuide calls fn for each program of the guide.
+// The cached guide is used if the cache is enabled,
+// otherwise the response is decoded incrementally.
+func (c *Client) walkGuide(req *http.Request, key string, fn func(Station, Prog) error) error {
+	err := c.walkGuideData(req, key, fn)
+	if errors.Is(err, ErrStopWalk) {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) walkGuideData(req *http.Request, key string, fn func(Station, Prog) error) error {
+	if gc := c.GuideCache(); gc != nil {
+		d, err := gc.get(c, req, key)
+		if err != nil {
+			return err
+		}
+		for _, s := range d.stations() {
+			meta := Station{ID: s.ID, Name: s.Name}
+			for _, p := range s.Programs() {
+				if err := fn(meta, p); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	sent := time.Now()
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	received := time.Now()
+	if resp.StatusCode != http.StatusOK {
+		return guideStatusError(resp)
 	}
 
+	g := NewGuideDecoder(resp.Body)
+	defer func() {
+		c.updateClockOffset(&stationsData{TTL: g.TTL, Srvtime: g.Srvtime}, resp.Header, sent, received)
+	}()
+	for {
+		s, p, err := g.NextProg()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(s, p); err != nil {
+			return err
+		}
+	}
+}
+
+// getStationsData sends the request for a program guide and decodes the response.
+// The clock offset of the client is updated from the response.
+func (c *Client) getStationsData(req *http.Request) (*stationsData, error) {
+	sent := time.Now()
 	resp, err := c.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	received := time.Now()
+	if resp.StatusCode != http.StatusOK {
+		return nil, guideStatusError(resp)
+	}
 
 	var d stationsData
 	if err = decodeStationsData(resp.Body, &d); err != nil {
 		return nil, err
 	}
-	return d.stations(), nil
+	c.updateClockOffset(&d, resp.Header, sent, received)
+	return &d, nil
+}
+
+// guideStatusError returns the error of a program guide response whose status is not OK.
+func guideStatusError(resp *http.Response) error {
+	return fmt.Errorf("failed to get the program guide: status=%d", resp.StatusCode)
 }
 
 // stationsData includes a response struct for client's users.
 type stationsData struct {
-	XMLName     xml.Name `xml:"radiko"`
+	XMLName xml.Name `xml:"radiko"`
+	// TTL is the lifetime of the response in seconds.
+	TTL int `xml:"ttl"`
+	// Srvtime is the unix time of the server when the response is generated.
+	Srvtime     int64 `xml:"srvtime"`
 	XMLStations struct {
 		XMLName  xml.Name `xml:"stations"`
 		Stations Stations `xml:"station"`
@@ -170,13 +392,18 @@ func (d *stationsData) stations() Stations {
 
 // decodeStationsData parses the XML-encoded data and stores the result.
 func decodeStationsData(input io.Reader, stations *stationsData) error {
-	b, err := ioutil.ReadAll(input)
-	if err != nil {
-		return err
-	}
-
-	if err = xml.Unmarshal(b, stations); err != nil {
-		return err
+	g := NewGuideDecoder(input)
+	for {
+		s, err := g.NextStation()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		stations.XMLStations.Stations = append(stations.XMLStations.Stations, s)
 	}
+	stations.TTL = g.TTL
+	stations.Srvtime = g.Srvtime
 	return nil
 }
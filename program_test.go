@@ -2,6 +2,9 @@ package radiko
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"testing"
@@ -146,3 +149,50 @@ func TestDecodeStationsData(t *testing.T) {
 		t.Errorf("expected number of stations %d, but %d.", expected, len(s))
 	}
 }
+
+func TestProg_Times(t *testing.T) {
+	p := Prog{Ft: "20161112233000", To: "20161113010000", Dur: "5400"}
+
+	start, err := p.StartTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	end, err := p.EndTime()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dur, err := p.Duration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := end.Sub(start); expected != dur {
+		t.Errorf("expected %s, but %s", expected, dur)
+	}
+}
+
+func TestStation_Programs(t *testing.T) {
+	for _, s := range loadTestStations(t) {
+		if len(s.Programs()) == 0 {
+			t.Errorf("Programs of %s is empty.", s.ID)
+		}
+	}
+}
+
+func TestGetStationsData_StatusError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "<html><body>Service Unavailable</body></html>")
+	}))
+	defer ts.Close()
+	client := newTestClient(t, ts)
+	ctx := context.Background()
+
+	expected := "failed to get the program guide: status=503"
+	if _, err := client.GetNowPrograms(ctx); err == nil || err.Error() != expected {
+		t.Errorf("expected %s, but %v", expected, err)
+	}
+	if _, err := client.GetStations(ctx, time.Date(2016, 11, 12, 23, 0, 0, 0, time.UTC)); err == nil || err.Error() != expected {
+		t.Errorf("expected %s, but %v", expected, err)
+	}
+}
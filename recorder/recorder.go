@@ -0,0 +1,338 @@
+// Package recorder records live streams of several radiko stations at once.
+package recorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/internal/hls"
+)
+
+const (
+	defaultRetryInterval   = 5 * time.Second
+	defaultFinalizeTimeout = time.Minute
+)
+
+// Progress is the state of the recording of a station.
+type Progress struct {
+	StationID string
+	// Bytes is the size of the audio written to the output.
+	Bytes int64
+	// Retries is the number of reconnections after errors.
+	Retries int
+	// Err is the last error, which may have been recovered by a retry.
+	Err error
+	// Done reports whether the recording of the station has finished.
+	Done bool
+}
+
+// Errors is the errors of the stations whose recordings failed, keyed by station ID.
+type Errors map[string]error
+
+func (e Errors) Error() string {
+	ids := make([]string, 0, len(e))
+	for id := range e {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	msgs := make([]string, len(ids))
+	for i, id := range ids {
+		msgs[i] = fmt.Sprintf("%s: %s", id, e[id])
+	}
+	return "recorder: " + strings.Join(msgs, "; ")
+}
+
+// MultiRecorder records the live streams of stations concurrently.
+// The stations share the auth_token and the connection pool of the managed client,
+// and are recorded from Start until End together.
+type MultiRecorder struct {
+	StationIDs []string
+	// Start is the time to start recording in radiko's clock (see radiko.Client.Now).
+	// Zero means immediately.
+	Start time.Time
+	// End is the time to stop recording in radiko's clock. It is required.
+	End time.Time
+	// Sink is the destination of the recordings. It is required.
+	// A recording is opened before Start, and is finalized after End,
+	// or aborted if nothing is recorded or writing fails.
+	Sink Sink
+	// OnProgress is called whenever the progress of a station changes.
+	// It may be called concurrently.
+	OnProgress func(Progress)
+	// PollInterval is the interval of reloading the live playlists.
+	// Zero means the target duration of the playlist.
+	PollInterval time.Duration
+	// RetryInterval is the interval of reconnecting a station after an error.
+	RetryInterval time.Duration
+	// FinalizeTimeout is the timeout of finalizing a recording, e.g. uploading it.
+	// Finalizing is not canceled by the context of Record.
+	FinalizeTimeout time.Duration
+
+	tokens *radiko.TokenManager
+	client *radiko.Client
+	now    func() time.Time
+
+	mu       sync.Mutex
+	progress map[string]*Progress
+}
+
+// NewMultiRecorder returns a new MultiRecorder of the stations,
+// which accesses radiko with the managed client.
+func NewMultiRecorder(tokens *radiko.TokenManager, ids ...string) *MultiRecorder {
+	return &MultiRecorder{
+		StationIDs:      ids,
+		RetryInterval:   defaultRetryInterval,
+		FinalizeTimeout: defaultFinalizeTimeout,
+		tokens:          tokens,
+		client:          tokens.Client(),
+		now:             tokens.Client().Now,
+	}
+}
+
+// Record records the stations until End or ctx is done.
+// A station is reconnected after errors of radiko until End,
+// and the recording of a station which fails is aborted without stopping the others.
+// The returned error is Errors of the stations which recorded nothing
+// or whose recording failed to be written or finalized.
+func (r *MultiRecorder) Record(ctx context.Context) error {
+	if r.End.IsZero() {
+		return errors.New("recorder: End is required")
+	}
+	if r.Sink == nil {
+		return errors.New("recorder: Sink is required")
+	}
+	if err := r.tokens.Authorize(ctx); err != nil {
+		return err
+	}
+	metadata := r.metadata(ctx)
+
+	r.mu.Lock()
+	r.progress = make(map[string]*Progress, len(r.StationIDs))
+	for _, id := range r.StationIDs {
+		r.progress[id] = &Progress{StationID: id}
+	}
+	r.mu.Unlock()
+
+	recCtx, cancel := context.WithTimeout(ctx, r.End.Sub(r.now()))
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs := Errors{}
+	for _, id := range r.StationIDs {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			if err := r.record(recCtx, metadata[id]); err != nil {
+				mu.Lock()
+				errs[id] = err
+				mu.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+	// Reaching End is not an error.
+	return ctx.Err()
+}
+
+// Progress returns the progress of the stations in the order of StationIDs.
+func (r *MultiRecorder) Progress() []Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l := make([]Progress, 0, len(r.progress))
+	for _, id := range r.StationIDs {
+		if p, ok := r.progress[id]; ok {
+			l = append(l, *p)
+		}
+	}
+	return l
+}
+
+// metadata returns the metadata of the recordings keyed by station ID.
+// The station and the program at Start are looked up in the guide of the area,
+// and only the station ID is set if not found.
+func (r *MultiRecorder) metadata(ctx context.Context) map[string]Metadata {
+	start := r.Start
+	if start.IsZero() {
+		start = r.now()
+	}
+	m := make(map[string]Metadata, len(r.StationIDs))
+	for _, id := range r.StationIDs {
+		m[id] = Metadata{Station: radiko.Station{ID: id}, Start: start}
+	}
+
+	stations, err := r.client.GetStations(ctx, start)
+	if err != nil {
+		return m
+	}
+	for _, s := range stations {
+		md, ok := m[s.ID]
+		if !ok {
+			continue
+		}
+		md.Station = radiko.Station{ID: s.ID, Name: s.Name}
+		for _, p := range s.Programs() {
+			ft, err1 := p.StartTime()
+			to, err2 := p.EndTime()
+			if err1 == nil && err2 == nil && !start.Before(ft) && start.Before(to) {
+				md.Prog = p
+				break
+			}
+		}
+		m[s.ID] = md
+	}
+	return m
+}
+
+// record records the station of the metadata until ctx is done.
+func (r *MultiRecorder) record(ctx context.Context, m Metadata) error {
+	stationID := m.Station.ID
+	defer r.update(stationID, func(p *Progress) { p.Done = true })
+
+	rec, err := r.Sink.Open(ctx, m)
+	if err != nil {
+		r.update(stationID, func(p *Progress) { p.Err = err })
+		return err
+	}
+	out := &countWriter{w: rec, fn: func(n int) {
+		r.update(stationID, func(p *Progress) { p.Bytes += int64(n) })
+	}}
+	err = r.copy(ctx, stationID, out)
+	if err == nil && out.n == 0 {
+		// Nothing is left, e.g. if canceled before Start.
+		rec.Abort()
+		return r.lastErr(stationID)
+	}
+	if err != nil {
+		rec.Abort()
+		return err
+	}
+
+	// ctx is done at End, so finalizing has its own deadline.
+	fctx, cancel := context.WithTimeout(context.Background(), r.finalizeTimeout())
+	defer cancel()
+	if err := rec.Finalize(fctx); err != nil {
+		r.update(stationID, func(p *Progress) { p.Err = err })
+		return err
+	}
+	return nil
+}
+
+// copy writes the live stream to w from Start until ctx is done,
+// reconnecting after errors of radiko.
+// It returns an error only if w fails.
+func (r *MultiRecorder) copy(ctx context.Context, stationID string, w *countWriter) error {
+	uri, err := r.client.LivePlaylistM3U8(ctx, stationID)
+	if err != nil {
+		r.update(stationID, func(p *Progress) { p.Err = err })
+	}
+	if !r.wait(ctx, r.Start.Sub(r.now())) {
+		return nil
+	}
+
+	// The fetcher is kept over reconnects, so that the segments still in the window
+	// are not recorded twice. It refreshes the auth_token shared by the stations
+	// when radiko rejects it.
+	f := hls.NewFetcher(r.tokens)
+	f.PollInterval = r.PollInterval
+	for {
+		if uri != "" {
+			err = f.Copy(ctx, w, uri, hls.Options{})
+			if w.err != nil {
+				return w.err
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+
+		if err != nil {
+			r.update(stationID, func(p *Progress) { p.Err = err })
+		}
+		if !r.wait(ctx, r.retryInterval()) {
+			return nil
+		}
+		r.update(stationID, func(p *Progress) { p.Retries++ })
+		if uri, err = r.client.LivePlaylistM3U8(ctx, stationID); err != nil {
+			uri = ""
+		}
+	}
+}
+
+// wait waits for d, and reports whether ctx is still alive.
+func (r *MultiRecorder) wait(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+func (r *MultiRecorder) retryInterval() time.Duration {
+	if r.RetryInterval > 0 {
+		return r.RetryInterval
+	}
+	return defaultRetryInterval
+}
+
+func (r *MultiRecorder) finalizeTimeout() time.Duration {
+	if r.FinalizeTimeout > 0 {
+		return r.FinalizeTimeout
+	}
+	return defaultFinalizeTimeout
+}
+
+func (r *MultiRecorder) update(stationID string, fn func(*Progress)) {
+	r.mu.Lock()
+	p := r.progress[stationID]
+	fn(p)
+	snapshot := *p
+	r.mu.Unlock()
+
+	if r.OnProgress != nil {
+		r.OnProgress(snapshot)
+	}
+}
+
+func (r *MultiRecorder) lastErr(stationID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.progress[stationID].Err
+}
+
+// countWriter counts the bytes written, and keeps the error of the writer
+// to tell it from the errors of radiko.
+type countWriter struct {
+	w   io.Writer
+	fn  func(n int)
+	n   int64
+	err error
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	if n > 0 {
+		c.fn(n)
+	}
+	if err != nil {
+		c.err = err
+	}
+	return n, err
+}
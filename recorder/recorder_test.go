@@ -0,0 +1,290 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/internal/radikotest"
+)
+
+// memRecording is an in-memory Recording which is safe for concurrent use.
+type memRecording struct {
+	mu        sync.Mutex
+	m         Metadata
+	b         bytes.Buffer
+	err       error
+	finalized bool
+	aborted   bool
+}
+
+func (r *memRecording) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return 0, r.err
+	}
+	return r.b.Write(p)
+}
+
+func (r *memRecording) Finalize(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.finalized = true
+	return nil
+}
+
+func (r *memRecording) Abort() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.aborted = true
+	return nil
+}
+
+func (r *memRecording) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.b.String()
+}
+
+// memSink opens a memRecording for each station.
+type memSink struct {
+	mu   sync.Mutex
+	recs map[string]*memRecording
+	// openErrs and writeErrs are the errors of opening and writing the recordings of the stations.
+	openErrs  map[string]error
+	writeErrs map[string]error
+}
+
+func (s *memSink) Open(ctx context.Context, m Metadata) (Recording, error) {
+	if err := s.openErrs[m.Station.ID]; err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.recs == nil {
+		s.recs = map[string]*memRecording{}
+	}
+	s.recs[m.Station.ID] = &memRecording{m: m, err: s.writeErrs[m.Station.ID]}
+	return s.recs[m.Station.ID], nil
+}
+
+func (s *memSink) recording(stationID string) *memRecording {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.recs[stationID]
+}
+
+func newTestRecorder(t *testing.T, ids ...string) (*radikotest.Server, *MultiRecorder, func()) {
+	fake := radikotest.NewServer()
+	radiko.SetHTTPClient(fake.HTTPClient())
+
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatalf("Failed to construct client: %s", err)
+	}
+	// Fetch a guide in advance so that the clock of the fake server is used from the start.
+	if _, err := client.GetNowPrograms(context.Background()); err != nil {
+		t.Fatalf("Failed to get the programs: %s", err)
+	}
+	r := NewMultiRecorder(radiko.NewTokenManager(client), ids...)
+	r.PollInterval = 10 * time.Millisecond
+	r.RetryInterval = 10 * time.Millisecond
+	return fake, r, func() {
+		fake.Close()
+		radiko.SetHTTPClient(&http.Client{Timeout: 120 * time.Second})
+	}
+}
+
+func TestMultiRecorder_Record(t *testing.T) {
+	ids := []string{"LFR", "TBS", "QRR"}
+	fake, r, cleanup := newTestRecorder(t, ids...)
+	defer cleanup()
+
+	sink := &memSink{}
+	r.Sink = sink
+	var mu sync.Mutex
+	var firstWrite time.Time
+	r.OnProgress = func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		if p.Bytes > 0 && firstWrite.IsZero() {
+			firstWrite = time.Now()
+		}
+	}
+	started := time.Now()
+	r.Start = r.now().Add(100 * time.Millisecond)
+	r.End = r.now().Add(300 * time.Millisecond)
+
+	if err := r.Record(context.Background()); err != nil {
+		t.Fatalf("Failed to record: %s", err)
+	}
+	if elapsed := firstWrite.Sub(started); elapsed < 90*time.Millisecond {
+		t.Errorf("Should start recording at Start: %s", elapsed)
+	}
+	if fake.Count("/v2/api/auth1") != 1 {
+		t.Errorf("Should share the auth_token: auth1=%d", fake.Count("/v2/api/auth1"))
+	}
+
+	progress := r.Progress()
+	if len(progress) != len(ids) {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+	for i, id := range ids {
+		rec := sink.recording(id)
+		out := rec.String()
+		if !strings.HasPrefix(out, radikotest.Segment("/live/"+id+"/segments/0.aac")) {
+			t.Errorf("unexpected output of %s: %s", id, out)
+		}
+		if !rec.finalized || rec.aborted {
+			t.Errorf("Should finalize the recording of %s", id)
+		}
+		if rec.m.Station.ID != id || !rec.m.Start.Equal(r.Start) {
+			t.Errorf("unexpected metadata of %s: %+v", id, rec.m)
+		}
+		p := progress[i]
+		if p.StationID != id || !p.Done || p.Bytes != int64(len(out)) {
+			t.Errorf("unexpected progress of %s: %+v", id, p)
+		}
+	}
+
+	// The programs at Start are in the guide of the fake server.
+	m := sink.recording("LFR").m
+	if m.Station.Name != "ニッポン放送" || m.Prog.Ft != "20161112230000" {
+		t.Errorf("unexpected metadata: %+v", m)
+	}
+}
+
+func TestMultiRecorder_Record_Refresh(t *testing.T) {
+	fake, r, cleanup := newTestRecorder(t, "LFR", "TBS")
+	defer cleanup()
+
+	sink := &memSink{}
+	r.Sink = sink
+	r.End = r.now().Add(400 * time.Millisecond)
+	revoked := make(chan struct{})
+	r.OnProgress = func(p Progress) {
+		if p.Bytes > 0 {
+			select {
+			case <-revoked:
+			default:
+				close(revoked)
+				fake.RevokeTokens()
+			}
+		}
+	}
+
+	if err := r.Record(context.Background()); err != nil {
+		t.Fatalf("Failed to record: %s", err)
+	}
+	if n := fake.Count("/v2/api/auth1"); n != 2 {
+		t.Errorf("Should refresh the shared auth_token once: auth1=%d", n)
+	}
+	for _, p := range r.Progress() {
+		if p.Retries != 0 {
+			t.Errorf("Should keep the connection of %s: %+v", p.StationID, p)
+		}
+	}
+	for _, id := range r.StationIDs {
+		out := sink.recording(id).String()
+		if strings.Count(out, "/segments/") < radikotest.LiveSegments+1 {
+			t.Errorf("Should keep recording %s after the refresh: %s", id, out)
+		}
+	}
+}
+
+func TestMultiRecorder_Record_Reconnect(t *testing.T) {
+	fake, r, cleanup := newTestRecorder(t, "LFR")
+	defer cleanup()
+
+	sink := &memSink{}
+	r.Sink = sink
+	r.End = r.now().Add(400 * time.Millisecond)
+	failed := make(chan struct{})
+	r.OnProgress = func(p Progress) {
+		if p.Bytes > 0 {
+			select {
+			case <-failed:
+			default:
+				close(failed)
+				fake.FailNext("/live/LFR/chunklist.m3u8", 1)
+			}
+		}
+	}
+
+	if err := r.Record(context.Background()); err != nil {
+		t.Fatalf("Failed to record: %s", err)
+	}
+	if p := r.Progress()[0]; p.Retries == 0 {
+		t.Errorf("Should reconnect: %+v", p)
+	}
+
+	out := sink.recording("LFR").String()
+	n := strings.Count(out, "/segments/")
+	if n < radikotest.LiveSegments+1 {
+		t.Fatalf("Should keep recording after the reconnect: %s", out)
+	}
+	var expected string
+	for i := 0; i < n; i++ {
+		expected += radikotest.Segment(fmt.Sprintf("/live/LFR/segments/%d.aac", i))
+	}
+	if out != expected {
+		t.Errorf("Should record each segment once: %s", out)
+	}
+}
+
+func TestMultiRecorder_Record_Errors(t *testing.T) {
+	_, r, cleanup := newTestRecorder(t, "LFR", "TBS", "QRR")
+	defer cleanup()
+
+	sink := &memSink{
+		openErrs:  map[string]error{"QRR": errors.New("permission denied")},
+		writeErrs: map[string]error{"TBS": errors.New("disk full")},
+	}
+	r.Sink = sink
+	r.End = r.now().Add(200 * time.Millisecond)
+
+	err := r.Record(context.Background())
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Should return Errors: %v", err)
+	}
+	if len(errs) != 2 || errs["TBS"] == nil || errs["QRR"] == nil {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	if expected := "recorder: QRR: permission denied; TBS: disk full"; err.Error() != expected {
+		t.Errorf("expected %s, but %s", expected, err)
+	}
+	if tbs := sink.recording("TBS"); !tbs.aborted || tbs.finalized {
+		t.Error("Should abort the failed recording.")
+	}
+	if lfr := sink.recording("LFR"); lfr.String() == "" || !lfr.finalized {
+		t.Error("Should record the other stations.")
+	}
+}
+
+func TestMultiRecorder_Record_Canceled(t *testing.T) {
+	_, r, cleanup := newTestRecorder(t, "LFR")
+	defer cleanup()
+
+	r.Sink = &memSink{}
+	r.End = r.now().Add(time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := r.Record(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v, but %v", context.DeadlineExceeded, err)
+	}
+
+	r.End = time.Time{}
+	if err := r.Record(context.Background()); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
@@ -0,0 +1,246 @@
+package recorder
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultS3Region      = "us-east-1"
+	defaultS3ContentType = "audio/aac"
+	// s3Service is the service name in the credential scope of the signature.
+	s3Service = "s3"
+)
+
+// S3Sink uploads recordings to an S3-compatible object store, e.g. Amazon S3 or MinIO.
+// A recording is spooled to a temporary file, and is uploaded with a single PUT on Finalize
+// to {Endpoint}/{Bucket}/{key} (path-style) with an AWS Signature Version 4.
+type S3Sink struct {
+	// Endpoint is the URL of the object store, e.g. "https://s3.ap-northeast-1.amazonaws.com".
+	Endpoint string
+	Bucket   string
+	// Region is the region in the signature. Defaults to "us-east-1".
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// Key is the template of the object keys. Defaults to DefaultPath.
+	Key string
+	// ContentType is the Content-Type of the objects. Defaults to "audio/aac".
+	ContentType string
+	// HTTPClient is the client of the uploads. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Open implements the Sink interface.
+func (s *S3Sink) Open(ctx context.Context, m Metadata) (Recording, error) {
+	key, err := expandPath(s.Key, m)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile("", "radiko-*.part")
+	if err != nil {
+		return nil, err
+	}
+	return &s3Recording{
+		sink: s,
+		key:  key,
+		file: f,
+		hash: sha256.New(),
+	}, nil
+}
+
+type s3Recording struct {
+	sink *S3Sink
+	key  string
+	file *os.File
+	hash hash.Hash
+	size int64
+}
+
+func (r *s3Recording) Write(p []byte) (int, error) {
+	n, err := r.file.Write(p)
+	r.hash.Write(p[:n])
+	r.size += int64(n)
+	return n, err
+}
+
+// Finalize uploads the spooled recording, and removes the temporary file.
+func (r *s3Recording) Finalize(ctx context.Context) error {
+	defer r.Abort()
+
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	req, err := r.sink.putRequest(ctx, r.key, ioutil.NopCloser(r.file), r.size, hex.EncodeToString(r.hash.Sum(nil)))
+	if err != nil {
+		return err
+	}
+
+	client := r.sink.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("recorder: failed to upload %s: %s: %s", r.key, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (r *s3Recording) Abort() error {
+	r.file.Close()
+	return os.Remove(r.file.Name())
+}
+
+// putRequest returns the signed request to put the object of the key.
+func (s *S3Sink) putRequest(ctx context.Context, key string, body io.ReadCloser, size int64, payloadHash string) (*http.Request, error) {
+	u := strings.TrimSuffix(s.Endpoint, "/") + "/" + s3Escape(s.Bucket+"/"+key)
+	req, err := http.NewRequest("PUT", u, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.ContentLength = size
+	if size == 0 {
+		// A zero ContentLength with a body is regarded as unknown.
+		req.Body = http.NoBody
+	}
+
+	contentType := s.ContentType
+	if contentType == "" {
+		contentType = defaultS3ContentType
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	region := s.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	signV4(req, payloadHash, s.AccessKeyID, s.SecretAccessKey, region, time.Now())
+	return req, nil
+}
+
+// signV4 signs the request with the AWS Signature Version 4 of S3.
+// The signed headers are Host, Content-Type, Range and X-Amz-*.
+func signV4(req *http.Request, payloadHash, accessKeyID, secretAccessKey, region string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	if req.Host != "" {
+		headers["host"] = req.Host
+	}
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || name == "range" || strings.HasPrefix(name, "x-amz-") {
+			headers[name] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, region, s3Service, "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := []byte("AWS4" + secretAccessKey)
+	for _, s := range []string{date, region, s3Service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		accessKeyID, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var pairs []string
+	for _, k := range keys {
+		values := append([]string(nil), q[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, queryEscape(k)+"="+queryEscape(v))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape escapes s as the URI encoding of AWS, keeping slashes.
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// queryEscape escapes s in the query of the canonical request, where slashes are escaped too.
+func queryEscape(s string) string {
+	return strings.ReplaceAll(s3Escape(s), "/", "%2F")
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+func hexSHA256(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
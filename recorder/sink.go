@@ -0,0 +1,178 @@
+package recorder
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/filename"
+)
+
+// DefaultPath is the default template of the paths of recordings.
+// See package filename for the placeholders.
+const DefaultPath = "{station}/{date}_{title}.aac"
+
+// Metadata describes a recording.
+type Metadata struct {
+	// Station is the recorded station, which has no programs.
+	Station radiko.Station
+	// Prog is the program on the air at Start, which is zero if unknown.
+	Prog radiko.Prog
+	// Start is the time when the recording starts.
+	Start time.Time
+}
+
+// Sink is the destination of recordings.
+type Sink interface {
+	// Open starts a recording of the metadata.
+	Open(ctx context.Context, m Metadata) (Recording, error)
+}
+
+// Recording is a recording being written to a Sink.
+// Either Finalize or Abort must be called after writing the audio.
+type Recording interface {
+	io.Writer
+	// Finalize completes the recording, e.g. commits the file or uploads the object.
+	Finalize(ctx context.Context) error
+	// Abort discards the recording as far as possible.
+	Abort() error
+}
+
+// expandPath returns the slash-separated path of the template for the metadata,
+// where {date} and {time} are of Start.
+func expandPath(tmpl string, m Metadata) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultPath
+	}
+	t, err := filename.Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	return t.Execute(filename.Data{Station: m.Station, Prog: m.Prog, Time: m.Start}), nil
+}
+
+// FileSink writes recordings to the local filesystem.
+// A recording is written to a temporary file in the same directory,
+// which is renamed to the path on Finalize.
+type FileSink struct {
+	// Dir is the directory of the recordings.
+	Dir string
+	// Path is the template of the path relative to Dir. Defaults to DefaultPath.
+	Path string
+}
+
+// Open implements the Sink interface.
+func (s *FileSink) Open(ctx context.Context, m Metadata) (Recording, error) {
+	p, err := expandPath(s.Path, m)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(s.Dir, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return nil, err
+	}
+	return &fileRecording{File: f, path: path}, nil
+}
+
+type fileRecording struct {
+	*os.File
+	path string
+}
+
+func (r *fileRecording) Finalize(ctx context.Context) error {
+	if err := r.File.Close(); err != nil {
+		os.Remove(r.File.Name())
+		return err
+	}
+	return os.Rename(r.File.Name(), r.path)
+}
+
+func (r *fileRecording) Abort() error {
+	r.File.Close()
+	return os.Remove(r.File.Name())
+}
+
+// WriterSink writes recordings to an io.Writer, e.g. os.Stdout.
+// Finalize and Abort do nothing, and do not close the writer.
+func WriterSink(w io.Writer) Sink {
+	return writerSink{w}
+}
+
+type writerSink struct {
+	w io.Writer
+}
+
+func (s writerSink) Open(ctx context.Context, m Metadata) (Recording, error) {
+	return writerRecording{s.w}, nil
+}
+
+type writerRecording struct {
+	io.Writer
+}
+
+func (writerRecording) Finalize(ctx context.Context) error { return nil }
+func (writerRecording) Abort() error                       { return nil }
+
+// MultiSink writes recordings to all of the sinks.
+type MultiSink []Sink
+
+// Open implements the Sink interface.
+// If a sink fails to open, the recordings opened so far are aborted.
+func (s MultiSink) Open(ctx context.Context, m Metadata) (Recording, error) {
+	recs := make(multiRecording, 0, len(s))
+	for _, sink := range s {
+		rec, err := sink.Open(ctx, m)
+		if err != nil {
+			recs.Abort()
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+type multiRecording []Recording
+
+func (r multiRecording) Write(p []byte) (int, error) {
+	for _, rec := range r {
+		n, err := rec.Write(p)
+		if err != nil {
+			return n, err
+		}
+		if n != len(p) {
+			return n, io.ErrShortWrite
+		}
+	}
+	return len(p), nil
+}
+
+// Finalize finalizes all of the recordings, and returns the first error.
+func (r multiRecording) Finalize(ctx context.Context) error {
+	var first error
+	for _, rec := range r {
+		if err := rec.Finalize(ctx); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Abort aborts all of the recordings, and returns the first error.
+func (r multiRecording) Abort() error {
+	var first error
+	for _, rec := range r {
+		if err := rec.Abort(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
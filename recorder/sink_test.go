@@ -0,0 +1,127 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/internal/util"
+)
+
+func testMetadata() Metadata {
+	return Metadata{
+		Station: radiko.Station{ID: "LFR", Name: "ニッポン放送"},
+		Prog:    radiko.Prog{Ft: "20161112230000", Title: "AC/DC\\特集"},
+		Start:   time.Date(2016, 11, 12, 23, 0, 0, 0, util.Location()).UTC(),
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	m := testMetadata()
+	cases := []struct {
+		tmpl     string
+		m        Metadata
+		expected string
+	}{
+		{"", m, "LFR/20161112_AC_DC_特集.aac"},
+		{"{station_name}/{ft}_{time}.aac", m, "ニッポン放送/20161112230000_230000.aac"},
+		{DefaultPath, Metadata{Station: m.Station, Start: m.Start}, "LFR/20161112_untitled.aac"},
+	}
+	for _, c := range cases {
+		p, err := expandPath(c.tmpl, c.m)
+		if err != nil {
+			t.Fatalf("Failed to expand %s: %s", c.tmpl, err)
+		}
+		if p != c.expected {
+			t.Errorf("expected %s, but %s", c.expected, p)
+		}
+	}
+
+	if _, err := (&FileSink{Path: "{unknown}.aac"}).Open(context.Background(), m); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sink := &FileSink{Dir: dir}
+	ctx := context.Background()
+	rec, err := sink.Open(ctx, testMetadata())
+	if err != nil {
+		t.Fatalf("Failed to open: %s", err)
+	}
+	if _, err := rec.Write([]byte("audio")); err != nil {
+		t.Fatalf("Failed to write: %s", err)
+	}
+	path := filepath.Join(dir, "LFR", "20161112_AC_DC_特集.aac")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Should not create the file until finalized.")
+	}
+	if err := rec.Finalize(ctx); err != nil {
+		t.Fatalf("Failed to finalize: %s", err)
+	}
+	if b, err := ioutil.ReadFile(path); err != nil || string(b) != "audio" {
+		t.Errorf("unexpected file: %q, %v", b, err)
+	}
+
+	rec, err = sink.Open(ctx, Metadata{Station: radiko.Station{ID: "TBS"}})
+	if err != nil {
+		t.Fatalf("Failed to open: %s", err)
+	}
+	if err := rec.Abort(); err != nil {
+		t.Fatalf("Failed to abort: %s", err)
+	}
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "TBS"))
+	if len(files) != 0 {
+		t.Errorf("Should remove the aborted recording: %v", files)
+	}
+}
+
+type openErrSink struct{}
+
+func (openErrSink) Open(ctx context.Context, m Metadata) (Recording, error) {
+	return nil, errors.New("unavailable")
+}
+
+func TestMultiSink(t *testing.T) {
+	var buf bytes.Buffer
+	mem := &memSink{}
+	sink := MultiSink{WriterSink(&buf), mem}
+	ctx := context.Background()
+
+	rec, err := sink.Open(ctx, testMetadata())
+	if err != nil {
+		t.Fatalf("Failed to open: %s", err)
+	}
+	if _, err := rec.Write([]byte("audio")); err != nil {
+		t.Fatalf("Failed to write: %s", err)
+	}
+	if err := rec.Finalize(ctx); err != nil {
+		t.Fatalf("Failed to finalize: %s", err)
+	}
+	if buf.String() != "audio" || mem.recording("LFR").String() != "audio" {
+		t.Errorf("Should write to all of the sinks: %q, %q", buf.String(), mem.recording("LFR").String())
+	}
+	if !mem.recording("LFR").finalized {
+		t.Error("Should finalize all of the recordings.")
+	}
+
+	mem = &memSink{}
+	if _, err := (MultiSink{mem, openErrSink{}}).Open(ctx, testMetadata()); err == nil {
+		t.Error("Should detect an error.")
+	}
+	if !mem.recording("LFR").aborted {
+		t.Error("Should abort the recordings opened before the error.")
+	}
+}
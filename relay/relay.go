@@ -0,0 +1,288 @@
+// Package relay serves radiko streams as continuous AAC over plain HTTP,
+// for players which cannot authenticate with radiko.
+//
+// Endpoints:
+//
+//	/live/{stationID}           the live stream of the station
+//	/timeshift/{stationID}/{ft} the timeshift program which starts at ft (YYYYMMDDhhmmss in JST)
+package relay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/internal/hls"
+	"github.com/yyoshiki41/go-radiko/internal/util"
+)
+
+const contentType = "audio/aac"
+
+// Server is an http.Handler which relays radiko streams.
+// Listeners of the same live station share a single upstream.
+type Server struct {
+	// PollInterval is the interval of reloading live playlists.
+	// Zero means the target duration of the playlist.
+	PollInterval time.Duration
+	// ListenerBuffer is the number of writes buffered for each listener.
+	// The upstream is written in chunks of up to 32 KiB, which are not aligned with the segments.
+	// A listener which falls behind further is disconnected.
+	ListenerBuffer int
+
+	tokens *radiko.TokenManager
+	client *radiko.Client
+
+	mu    sync.Mutex
+	lives map[string]*broadcast
+}
+
+// New returns a new Server which accesses radiko with the managed client.
+func New(tokens *radiko.TokenManager) *Server {
+	return &Server{
+		ListenerBuffer: 16,
+		tokens:         tokens,
+		client:         tokens.Client(),
+		lives:          map[string]*broadcast{},
+	}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "live" && parts[1] != "":
+		s.serveLive(w, r, parts[1])
+	case len(parts) == 3 && parts[0] == "timeshift" && parts[1] != "":
+		s.serveTimeshift(w, r, parts[1], parts[2])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// fetcher returns a fetcher which refreshes the shared auth_token when radiko rejects it,
+// so that the upstreams survive the expiry of the token.
+func (s *Server) fetcher() *hls.Fetcher {
+	f := hls.NewFetcher(s.tokens)
+	f.PollInterval = s.PollInterval
+	return f
+}
+
+func (s *Server) serveLive(w http.ResponseWriter, r *http.Request, stationID string) {
+	ctx := r.Context()
+	b, ch, err := s.subscribe(ctx, stationID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer s.unsubscribe(stationID, b, ch)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == "HEAD" {
+		return
+	}
+	fw := newFlushWriter(w)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := fw.Write(data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) serveTimeshift(w http.ResponseWriter, r *http.Request, stationID, ft string) {
+	ctx := r.Context()
+	start, err := util.ParseDatetime(ft)
+	if err != nil {
+		http.Error(w, "invalid ft, use YYYYMMDDhhmmss", http.StatusBadRequest)
+		return
+	}
+	if err := s.tokens.Authorize(ctx); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	uri, err := s.client.TimeshiftPlaylistM3U8(ctx, stationID, start)
+	var terr *radiko.TimefreeError
+	if errors.Is(err, radiko.ErrProgramNotFound) || errors.As(err, &terr) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	if r.Method == "HEAD" {
+		return
+	}
+	// The response has already started, so errors can not be reported.
+	s.fetcher().Copy(ctx, newFlushWriter(w), uri, hls.Options{})
+}
+
+// subscribe adds a listener to the broadcast of the live station,
+// starting the upstream if nobody listens to the station.
+func (s *Server) subscribe(ctx context.Context, stationID string) (*broadcast, chan []byte, error) {
+	s.mu.Lock()
+	if b, ok := s.lives[stationID]; ok {
+		ch := b.add(s.ListenerBuffer)
+		s.mu.Unlock()
+		return b, ch, nil
+	}
+	s.mu.Unlock()
+
+	if err := s.tokens.Authorize(ctx); err != nil {
+		return nil, nil, err
+	}
+	uri, err := s.client.LivePlaylistM3U8(ctx, stationID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Another listener may have started the upstream in the meantime.
+	if b, ok := s.lives[stationID]; ok {
+		return b, b.add(s.ListenerBuffer), nil
+	}
+
+	upstreamCtx, cancel := context.WithCancel(context.Background())
+	b := newBroadcast(cancel)
+	s.lives[stationID] = b
+	go func() {
+		s.fetcher().Copy(upstreamCtx, b, uri, hls.Options{})
+		s.remove(stationID, b)
+		b.close()
+	}()
+	return b, b.add(s.ListenerBuffer), nil
+}
+
+// unsubscribe removes the listener and stops the upstream
+// when the last listener leaves.
+func (s *Server) unsubscribe(stationID string, b *broadcast, ch chan []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !b.remove(ch) {
+		return
+	}
+	if s.lives[stationID] == b {
+		delete(s.lives, stationID)
+	}
+	b.cancel()
+}
+
+func (s *Server) remove(stationID string, b *broadcast) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lives[stationID] == b {
+		delete(s.lives, stationID)
+	}
+}
+
+// broadcast is an io.Writer which fans out the written chunks to listeners.
+type broadcast struct {
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	listeners map[chan []byte]struct{}
+	closed    bool
+}
+
+func newBroadcast(cancel context.CancelFunc) *broadcast {
+	return &broadcast{
+		cancel:    cancel,
+		listeners: map[chan []byte]struct{}{},
+	}
+}
+
+// Write implements the io.Writer interface.
+// Listeners which can not keep up are disconnected.
+func (b *broadcast) Write(p []byte) (int, error) {
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.listeners {
+		select {
+		case ch <- data:
+		default:
+			delete(b.listeners, ch)
+			close(ch)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *broadcast) add(buffer int) chan []byte {
+	ch := make(chan []byte, buffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch
+	}
+	b.listeners[ch] = struct{}{}
+	return ch
+}
+
+// remove removes the listener and reports whether no listener remains.
+func (b *broadcast) remove(ch chan []byte) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.listeners[ch]; ok {
+		delete(b.listeners, ch)
+		close(ch)
+	}
+	return len(b.listeners) == 0
+}
+
+func (b *broadcast) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.listeners {
+		delete(b.listeners, ch)
+		close(ch)
+	}
+}
+
+// flushWriter flushes the response after every write.
+type flushWriter struct {
+	w io.Writer
+	f http.Flusher
+}
+
+func newFlushWriter(w http.ResponseWriter) *flushWriter {
+	f, _ := w.(http.Flusher)
+	return &flushWriter{w: w, f: f}
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if fw.f != nil {
+		fw.f.Flush()
+	}
+	return n, err
+}
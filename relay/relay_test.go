@@ -0,0 +1,149 @@
+package relay
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	radiko "github.com/yyoshiki41/go-radiko"
+	"github.com/yyoshiki41/go-radiko/internal/radikotest"
+)
+
+func newTestRelay(t *testing.T) (*radikotest.Server, *httptest.Server, func()) {
+	fake := radikotest.NewServer()
+	radiko.SetHTTPClient(fake.HTTPClient())
+
+	client, err := radiko.New("")
+	if err != nil {
+		t.Fatalf("Failed to construct client: %s", err)
+	}
+	s := New(radiko.NewTokenManager(client))
+	s.PollInterval = 10 * time.Millisecond
+	ts := httptest.NewServer(s)
+
+	return fake, ts, func() {
+		ts.Close()
+		fake.Close()
+		radiko.SetHTTPClient(&http.Client{Timeout: 120 * time.Second})
+	}
+}
+
+func TestServer_Live(t *testing.T) {
+	fake, ts, cleanup := newTestRelay(t)
+	defer cleanup()
+
+	var bodies []io.ReadCloser
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(ts.URL + "/live/LFR")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status: %d", resp.StatusCode)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != contentType {
+			t.Errorf("unexpected Content-Type: %s", ct)
+		}
+		bodies = append(bodies, resp.Body)
+	}
+
+	for _, body := range bodies {
+		readUntil(t, body, radikotest.Segment("/live/LFR/segments/5.aac"))
+	}
+
+	if n := fake.Count("/v2/api/playlist_create/"); n != 1 {
+		t.Errorf("upstream should be shared, but playlist_create was called %d times", n)
+	}
+}
+
+// readUntil reads body until s appears.
+func readUntil(t *testing.T, body io.Reader, s string) {
+	t.Helper()
+	b := make([]byte, 0, 1024)
+	buf := make([]byte, 256)
+	for !strings.Contains(string(b), s) {
+		n, err := body.Read(buf)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v (%s)", s, err, b)
+		}
+		b = append(b, buf[:n]...)
+	}
+}
+
+func TestServer_Live_RefreshToken(t *testing.T) {
+	fake, ts, cleanup := newTestRelay(t)
+	defer cleanup()
+
+	resp, err := http.Get(ts.URL + "/live/LFR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	readUntil(t, resp.Body, radikotest.Segment("/live/LFR/segments/2.aac"))
+	// The auth_token expires in the middle of the stream.
+	fake.RevokeTokens()
+	readUntil(t, resp.Body, radikotest.Segment("/live/LFR/segments/8.aac"))
+
+	if n := fake.Count("/v2/api/auth1"); n != 2 {
+		t.Errorf("Should refresh the auth_token once: auth1=%d", n)
+	}
+	if n := fake.Count("/v2/api/playlist_create/"); n != 1 {
+		t.Errorf("Should keep the upstream: playlist_create=%d", n)
+	}
+}
+
+func TestServer_Timeshift(t *testing.T) {
+	fake, ts, cleanup := newTestRelay(t)
+	defer cleanup()
+	// The program from 23:00 to 23:30 has ended at 23:40 JST.
+	fake.SetNow(time.Date(2016, 11, 12, 14, 40, 0, 0, time.UTC))
+
+	resp, err := http.Get(ts.URL + "/timeshift/LFR/20161112230000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expected string
+	for _, s := range []string{"0", "1", "2"} {
+		expected += radikotest.Segment("/tf/segments/" + s + ".aac")
+	}
+	if string(b) != expected {
+		t.Errorf("expected %s, but %s", expected, b)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	_, ts, cleanup := newTestRelay(t)
+	defer cleanup()
+
+	cases := []struct {
+		path     string
+		expected int
+	}{
+		{"/", http.StatusNotFound},
+		{"/live/", http.StatusNotFound},
+		{"/timeshift/LFR/invalid", http.StatusBadRequest},
+		{"/timeshift/LFR/20161112230100", http.StatusNotFound},
+	}
+	for _, c := range cases {
+		resp, err := http.Get(ts.URL + c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != c.expected {
+			t.Errorf("%s: expected %d, but %d", c.path, c.expected, resp.StatusCode)
+		}
+	}
+}
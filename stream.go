@@ -1,10 +1,11 @@
 package radiko
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
-	"net/http"
 	"path"
 )
 
@@ -15,11 +16,23 @@ type URLItem struct {
 }
 
 // GetStreamMultiURL returns a slice of the stream url.
+//
+// Deprecated: Use Client.GetStreamMultiURL, which supports context and custom HTTP clients.
 func GetStreamMultiURL(stationID string) ([]URLItem, error) {
-	endpoint := path.Join(apiV2, "station/stream_multi",
+	return defaultClient().GetStreamMultiURL(context.Background(), stationID)
+}
+
+// GetStreamMultiURL returns a slice of the stream url.
+func (c *Client) GetStreamMultiURL(ctx context.Context, stationID string) ([]URLItem, error) {
+	apiEndpoint := path.Join(apiV2, "station/stream_multi",
 		fmt.Sprintf("%s.xml", stationID))
 
-	resp, err := http.Get("http://radiko.jp/" + endpoint)
+	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -64,17 +77,33 @@ type streamSmhURLData struct {
 }
 
 // GetStreamSmhMultiURL returns a slice of the stream smh url.
+//
+// Deprecated: Use Client.GetStreamSmhMultiURL, which supports context and custom HTTP clients.
 func GetStreamSmhMultiURL(stationID string) ([]SmhURLItem, error) {
-	endpoint := path.Join(apiV2, "station/stream_smh_multi",
+	return defaultClient().GetStreamSmhMultiURL(context.Background(), stationID)
+}
+
+// GetStreamSmhMultiURL returns a slice of the stream smh url.
+func (c *Client) GetStreamSmhMultiURL(ctx context.Context, stationID string) ([]SmhURLItem, error) {
+	apiEndpoint := path.Join(apiV2, "station/stream_smh_multi",
 		fmt.Sprintf("%s.xml", stationID))
 
-	resp, err := http.Get("http://radiko.jp/" + endpoint)
+	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	b, err := ioutil.ReadAll(resp.Body)
+	return decodeStreamSmhURLData(resp.Body)
+}
+
+func decodeStreamSmhURLData(input io.Reader) ([]SmhURLItem, error) {
+	b, err := ioutil.ReadAll(input)
 	if err != nil {
 		return nil, err
 	}
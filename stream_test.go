@@ -1,6 +1,10 @@
 package radiko
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -30,3 +34,38 @@ func TestGetLiveURL(t *testing.T) {
 		t.Error("A live url is empty.")
 	}
 }
+
+func TestClient_GetStreamMultiURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/station/stream_multi/LFR.xml" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("User-Agent") != userAgent {
+			t.Errorf("unexpected User-Agent: %s", r.Header.Get("User-Agent"))
+		}
+		fmt.Fprint(w, `<url><item areafree="0">rtmpe://f-radiko.smartstream.ne.jp/LFR/_definst_/simul-stream.stream</item><item areafree="1">rtmpe://f-radiko.smartstream.ne.jp/LFR/_definst_/simul-stream.stream</item></url>`)
+	}))
+	defer ts.Close()
+
+	items, err := newTestClient(t, ts).GetStreamMultiURL(context.Background(), "LFR")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Areafree || !items[1].Areafree {
+		t.Errorf("unexpected items: %v", items)
+	}
+}
+
+func TestClient_GetStreamSmhMultiURL_Canceled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<urls></urls>`)
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := newTestClient(t, ts).GetStreamSmhMultiURL(ctx, "LFR"); err == nil {
+		t.Error("Should detect the canceled context.")
+	}
+}
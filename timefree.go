@@ -0,0 +1,149 @@
+package radiko
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// TimefreePeriod is how long programs are available in timefree.
+	TimefreePeriod = 7 * 24 * time.Hour
+	// Timefree30Period is how long programs are available in timefree 30,
+	// the extended timefree of the premium members.
+	Timefree30Period = 30 * 24 * time.Hour
+)
+
+// TimefreeStatus is the availability of a program in timefree.
+type TimefreeStatus int
+
+const (
+	// TimefreeAvailable means the program is available.
+	TimefreeAvailable TimefreeStatus = iota
+	// TimefreeExpired means the program started more than Timefree30Period ago.
+	TimefreeExpired
+	// TimefreeNotYetAired means the program has not ended yet.
+	TimefreeNotYetAired
+	// TimefreeNotSupported means the station does not provide timefree.
+	TimefreeNotSupported
+	// TimefreePremiumOnly means the program is only available in timefree 30,
+	// i.e. it started more than TimefreePeriod ago.
+	TimefreePremiumOnly
+)
+
+func (s TimefreeStatus) String() string {
+	switch s {
+	case TimefreeAvailable:
+		return "available"
+	case TimefreeExpired:
+		return "expired"
+	case TimefreeNotYetAired:
+		return "not yet aired"
+	case TimefreeNotSupported:
+		return "not supported"
+	case TimefreePremiumOnly:
+		return "premium only"
+	}
+	return fmt.Sprintf("TimefreeStatus(%d)", int(s))
+}
+
+// TimefreeError is returned when a program is not available in timefree.
+type TimefreeError struct {
+	StationID string
+	Prog      Prog
+	Status    TimefreeStatus
+}
+
+func (e *TimefreeError) Error() string {
+	return fmt.Sprintf("timefree of %s at %s is %s", e.StationID, e.Prog.Ft, e.Status)
+}
+
+// EnableTimefree30 enables timefree 30 after checking that the logged-in session
+// is of an area-free premium member (LoginOK.Areafree), or returns ErrTimefree30NotAllowed.
+//
+// In timefree 30, programs up to Timefree30Period ago are available,
+// area-free playlist create urls are preferred,
+// and programs are looked up in the guide of the station so that stations in other areas are found.
+func (c *Client) EnableTimefree30(ctx context.Context) error {
+	status, err := c.loginCheck(ctx)
+	if err != nil {
+		return err
+	}
+	if ok, isOK := status.(LoginOK); !isOK || ok.Areafree != "1" {
+		return ErrTimefree30NotAllowed
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timefree30 = true
+	return nil
+}
+
+// DisableTimefree30 disables timefree 30.
+func (c *Client) DisableTimefree30() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timefree30 = false
+}
+
+// Timefree30 reports whether timefree 30 is enabled.
+func (c *Client) Timefree30() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.timefree30
+}
+
+// TimefreeStatus returns the availability of the program of the station in timefree.
+// The age of the program is measured by the clock of radiko (see Client.Now).
+// A program on the air is not available until it ends.
+// TimefreePremiumOnly is not returned in timefree 30.
+func (c *Client) TimefreeStatus(ctx context.Context, stationID string, prog Prog) (TimefreeStatus, error) {
+	start, err := prog.StartTime()
+	if err != nil {
+		return 0, err
+	}
+	end, err := prog.EndTime()
+	if err != nil {
+		return 0, err
+	}
+	// The status by the time does not need the stream urls.
+	switch status := timefreeStatus(c.Now(), start, end, nil, c.Timefree30()); status {
+	case TimefreeExpired, TimefreeNotYetAired:
+		return status, nil
+	}
+
+	urls, err := c.stationStreamURLs(ctx, stationID)
+	if err != nil {
+		return 0, err
+	}
+	return timefreeStatus(c.Now(), start, end, urls, c.Timefree30()), nil
+}
+
+// timefreeStatus returns the availability of the program from start to end.
+// The station is regarded as supporting timefree if urls is empty.
+func timefreeStatus(now, start, end time.Time, urls []stationStreamURL, timefree30 bool) TimefreeStatus {
+	if now.Before(end) {
+		return TimefreeNotYetAired
+	}
+	if now.Sub(start) > Timefree30Period {
+		return TimefreeExpired
+	}
+
+	if len(urls) > 0 && !supportsTimefree(urls) {
+		return TimefreeNotSupported
+	}
+	if now.Sub(start) > TimefreePeriod && !timefree30 {
+		return TimefreePremiumOnly
+	}
+	return TimefreeAvailable
+}
+
+// supportsTimefree reports whether the stream urls include a timefree url.
+func supportsTimefree(urls []stationStreamURL) bool {
+	for _, u := range urls {
+		if u.Timefree == "1" && u.PlaylistCreateURL != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package radiko
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yyoshiki41/go-radiko/internal/util"
+)
+
+func TestTimefreeStatus(t *testing.T) {
+	now := time.Date(2016, 11, 12, 23, 23, 47, 0, util.Location())
+	timefree := []stationStreamURL{{Timefree: "1", PlaylistCreateURL: "https://radiko.jp/tf/playlist.m3u8"}}
+	live := []stationStreamURL{{Timefree: "0", PlaylistCreateURL: "https://radiko.jp/live.m3u8"}}
+
+	cases := []struct {
+		start    time.Time
+		end      time.Time
+		urls     []stationStreamURL
+		expected TimefreeStatus
+	}{
+		{now.Add(-time.Hour), now.Add(-time.Minute), timefree, TimefreeAvailable},
+		{now.Add(-time.Minute), now, nil, TimefreeAvailable},
+		{now.Add(time.Minute), now.Add(time.Hour), timefree, TimefreeNotYetAired},
+		// The program is on the air.
+		{now.Add(-time.Hour), now.Add(time.Minute), timefree, TimefreeNotYetAired},
+		{now.Add(-TimefreePeriod - time.Hour), now.Add(-TimefreePeriod), timefree, TimefreePremiumOnly},
+		{now.Add(-Timefree30Period - time.Hour), now.Add(-Timefree30Period), timefree, TimefreeExpired},
+		{now.Add(-time.Hour), now.Add(-time.Minute), live, TimefreeNotSupported},
+		{now.Add(-Timefree30Period - time.Hour), now.Add(-Timefree30Period), live, TimefreeExpired},
+	}
+	for _, c := range cases {
+		if status := timefreeStatus(now, c.start, c.end, c.urls, false); status != c.expected {
+			t.Errorf("%s: expected %s, but %s", c.start, c.expected, status)
+		}
+	}
+}
+
+func newTimefreeTestServer(t *testing.T, timefree string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v3/program/date/"):
+			// The guide is served at 23:40:00 JST, after the first program of LFR.
+			b, err := ioutil.ReadFile(filepath.Join(testdataDir, "stations.xml"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Write(bytes.Replace(b, []byte("<srvtime>1478960627</srvtime>"), []byte("<srvtime>1478961600</srvtime>"), 1))
+		case strings.HasPrefix(r.URL.Path, "/v3/station/stream/pc_html5/"):
+			fmt.Fprintf(w, `<urls><url timefree="%s" areafree="0"><playlist_create_url>https://radiko.jp/tf/playlist.m3u8</playlist_create_url></url></urls>`, timefree)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestClient_TimefreeStatus(t *testing.T) {
+	ts := newTimefreeTestServer(t, "0")
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	ctx := context.Background()
+	now := client.Now()
+	cases := []struct {
+		start    time.Time
+		expected TimefreeStatus
+	}{
+		// The stream urls are not requested.
+		{now.Add(time.Hour), TimefreeNotYetAired},
+		{now.Add(-30 * time.Minute), TimefreeNotYetAired},
+		{now.Add(-Timefree30Period - time.Hour), TimefreeExpired},
+		{now.Add(-time.Hour), TimefreeNotSupported},
+	}
+	for _, c := range cases {
+		prog := Prog{Ft: util.Datetime(c.start), To: util.Datetime(c.start.Add(time.Hour))}
+		status, err := client.TimefreeStatus(ctx, "LFR", prog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status != c.expected {
+			t.Errorf("expected %s, but %s", c.expected, status)
+		}
+	}
+
+	if _, err := client.TimefreeStatus(ctx, "LFR", Prog{Ft: "invalid"}); err == nil {
+		t.Error("Should detect an error.")
+	}
+}
+
+func TestClient_TimeshiftPlaylistM3U8_NotSupported(t *testing.T) {
+	ts := newTimefreeTestServer(t, "0")
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	start := time.Date(2016, 11, 12, 23, 0, 0, 0, util.Location())
+	_, err := client.TimeshiftPlaylistM3U8(context.Background(), "LFR", start)
+	var terr *TimefreeError
+	if !errors.As(err, &terr) {
+		t.Fatalf("Should return a TimefreeError: %v", err)
+	}
+	if terr.Status != TimefreeNotSupported || terr.StationID != "LFR" || terr.Prog.Ft != "20161112230000" {
+		t.Errorf("unexpected error: %+v", terr)
+	}
+
+	// The program is on the air.
+	start = time.Date(2016, 11, 12, 23, 30, 0, 0, util.Location())
+	_, err = client.TimeshiftPlaylistM3U8(context.Background(), "LFR", start)
+	if !errors.As(err, &terr) || terr.Status != TimefreeNotYetAired {
+		t.Errorf("expected %s, but %v", TimefreeNotYetAired, err)
+	}
+}
+
+func TestTimefreeStatus_Timefree30(t *testing.T) {
+	now := time.Date(2016, 11, 12, 23, 23, 47, 0, util.Location())
+	cases := []struct {
+		start    time.Time
+		expected TimefreeStatus
+	}{
+		{now.Add(-time.Hour), TimefreeAvailable},
+		{now.Add(-TimefreePeriod - time.Hour), TimefreeAvailable},
+		{now.Add(-Timefree30Period - time.Hour), TimefreeExpired},
+	}
+	for _, c := range cases {
+		if status := timefreeStatus(now, c.start, c.start.Add(30*time.Minute), nil, true); status != c.expected {
+			t.Errorf("%s: expected %s, but %s", c.start, c.expected, status)
+		}
+	}
+}
+
+func TestTimefreePlaylistCreateURL(t *testing.T) {
+	urls := []stationStreamURL{
+		{Timefree: "0", Arefree: "1", PlaylistCreateURL: "live"},
+		{Timefree: "1", Arefree: "1", PlaylistCreateURL: "areafree"},
+		{Timefree: "1", Arefree: "0", PlaylistCreateURL: "arealocked"},
+	}
+	if u := timefreePlaylistCreateURL(urls, false); u != "arealocked" {
+		t.Errorf("expected arealocked, but %s", u)
+	}
+	if u := timefreePlaylistCreateURL(urls, true); u != "areafree" {
+		t.Errorf("expected areafree, but %s", u)
+	}
+	if u := timefreePlaylistCreateURL(urls[2:], true); u != "arealocked" {
+		t.Errorf("Should fall back to arealocked: %s", u)
+	}
+	if u := timefreePlaylistCreateURL(nil, true); u != timeshiftPlaylistEndpoint {
+		t.Errorf("Should fall back to the default endpoint: %s", u)
+	}
+}
+
+func TestClient_EnableTimefree30(t *testing.T) {
+	var status int
+	var body string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ap/member/webapi/member/login/check" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	cases := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, `{"status":"200","paid_member":"1","areafree":"0"}`},
+		{http.StatusBadRequest, `{"status":"400","message":"not logged in"}`},
+	}
+	for _, c := range cases {
+		status, body = c.status, c.body
+		if err := client.EnableTimefree30(context.Background()); !errors.Is(err, ErrTimefree30NotAllowed) {
+			t.Errorf("Should return ErrTimefree30NotAllowed: %v", err)
+		}
+		if client.Timefree30() {
+			t.Error("Should not enable timefree 30.")
+		}
+	}
+
+	status, body = http.StatusOK, `{"status":"200","paid_member":"1","areafree":"1"}`
+	if err := client.EnableTimefree30(context.Background()); err != nil {
+		t.Fatalf("Failed to enable timefree 30: %s", err)
+	}
+	if !client.Timefree30() {
+		t.Error("Should enable timefree 30.")
+	}
+	client.DisableTimefree30()
+	if client.Timefree30() {
+		t.Error("Should disable timefree 30.")
+	}
+}
+
+func TestClient_Timefree30Guides(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		http.ServeFile(w, r, filepath.Join(testdataDir, "stations.xml"))
+	}))
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	client.timefree30 = true
+	ctx := context.Background()
+	date := time.Date(2016, 11, 12, 23, 0, 0, 0, util.Location())
+
+	prog, err := client.GetProgramByStartTime(ctx, "LFR", date)
+	if err != nil {
+		t.Fatalf("Failed to get the program: %s", err)
+	}
+	if prog.Ft != "20161112230000" {
+		t.Errorf("unexpected program: %s", prog.Ft)
+	}
+
+	if _, err := client.GetAreaStations(ctx, "JP27", date); err != nil {
+		t.Fatalf("Failed to get the stations: %s", err)
+	}
+	if _, err := client.GetAreaStations(ctx, "", date); err == nil {
+		t.Error("Should detect an error.")
+	}
+
+	expected := []string{
+		"/v3/program/station/date/20161112/LFR.xml",
+		"/v3/program/date/20161112/JP27.xml",
+	}
+	if len(paths) != len(expected) {
+		t.Fatalf("expected %v, but %v", expected, paths)
+	}
+	for i := range expected {
+		if paths[i] != expected[i] {
+			t.Errorf("expected %s, but %s", expected[i], paths[i])
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 const timeshiftPlaylistEndpoint = "https://tf-f-rpaa-radiko.smartstream.ne.jp/tf/playlist.m3u8"
 
 // TimeshiftPlaylistM3U8 returns uri.
+// It returns a *TimefreeError if the program is not available in timefree.
 func (c *Client) TimeshiftPlaylistM3U8(ctx context.Context, stationID string, start time.Time) (string, error) {
 	if ctx == nil {
 		return "", errors.New("Context is nil")
@@ -32,12 +33,23 @@ func (c *Client) TimeshiftPlaylistM3U8(ctx context.Context, stationID string, st
 		return "", err
 	}
 
-	endpoint, err := c.timeshiftPlaylistEndpoint(ctx, stationID)
+	urls, err := c.stationStreamURLs(ctx, stationID)
 	if err != nil {
 		return "", err
 	}
+	progStart, err := prog.StartTime()
+	if err != nil {
+		return "", err
+	}
+	progEnd, err := prog.EndTime()
+	if err != nil {
+		return "", err
+	}
+	if status := timefreeStatus(c.Now(), progStart, progEnd, urls, c.Timefree30()); status != TimefreeAvailable {
+		return "", &TimefreeError{StationID: stationID, Prog: *prog, Status: status}
+	}
 
-	u, err := url.Parse(endpoint)
+	u, err := url.Parse(timefreePlaylistCreateURL(urls, c.Timefree30()))
 	if err != nil {
 		return "", err
 	}
@@ -65,44 +77,54 @@ func (c *Client) TimeshiftPlaylistM3U8(ctx context.Context, stationID string, st
 	return "", lastErr
 }
 
-func (c *Client) timeshiftPlaylistEndpoint(ctx context.Context, stationID string) (string, error) {
+// stationStreamURLs returns the stream urls of the station for radiko's HTML5 player.
+func (c *Client) stationStreamURLs(ctx context.Context, stationID string) ([]stationStreamURL, error) {
 	apiEndpoint := path.Join(apiV3, "station/stream/pc_html5", stationID+".xml")
 	req, err := c.newRequest(ctx, "GET", apiEndpoint, &Params{})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	resp, err := c.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("failed to get station stream info: status=%d", resp.StatusCode)
+		return nil, fmt.Errorf("failed to get station stream info: status=%d", resp.StatusCode)
 	}
 
 	var data stationStreamData
 	if err := xml.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return "", err
+		return nil, err
 	}
+	return data.URLs, nil
+}
 
+// timefreePlaylistCreateURL returns the playlist create url of timefree.
+// Area-locked urls are preferred for non-premium flow compatibility,
+// and area-free urls are preferred in timefree 30.
+func timefreePlaylistCreateURL(urls []stationStreamURL, areafree bool) string {
+	want := "0"
+	if areafree {
+		want = "1"
+	}
 	fallback := ""
-	for _, u := range data.URLs {
+	for _, u := range urls {
 		if u.Timefree != "1" || u.PlaylistCreateURL == "" {
 			continue
 		}
-		// Prefer area-locked endpoint first for non-premium flow compatibility.
-		if u.Arefree == "0" {
-			return u.PlaylistCreateURL, nil
+		if u.Arefree == want {
+			return u.PlaylistCreateURL
 		}
 		if fallback == "" {
 			fallback = u.PlaylistCreateURL
 		}
 	}
 	if fallback != "" {
-		return fallback, nil
+		return fallback
 	}
-	return timeshiftPlaylistEndpoint, nil
+	return timeshiftPlaylistEndpoint
 }
 
 type stationStreamData struct {
@@ -116,27 +138,10 @@ type stationStreamURL struct {
 }
 
 func (c *Client) requestTimeshiftPlaylistURI(ctx context.Context, method, endpoint string) (string, error) {
-	req, err := http.NewRequest(method, endpoint, nil)
+	req, err := c.newStreamRequest(ctx, method, endpoint)
 	if err != nil {
 		return "", err
 	}
-	req = req.WithContext(ctx)
-	req.Header.Set("User-Agent", userAgent)
-	req.Header.Set("Accept", "*/*")
-	req.Header.Set("Cache-Control", "no-cache")
-	req.Header.Set("pragma", "no-cache")
-	req.Header.Set("Origin", defaultEndpoint)
-	req.Header.Set("Referer", defaultEndpoint+"/")
-	req.Header.Set(radikoAppHeader, radikoApp)
-	req.Header.Set(radikoAppVersionHeader, radikoAppVersion)
-	req.Header.Set(radikoUserHeader, radikoUser)
-	req.Header.Set(radikoDeviceHeader, radikoDevice)
-	if c.AreaID() != "" {
-		req.Header.Set("X-Radiko-AreaId", c.AreaID())
-	}
-	if c.AuthToken() != "" {
-		req.Header.Set(radikoAuthTokenHeader, c.AuthToken())
-	}
 
 	resp, err := c.Do(req)
 	if err != nil {
@@ -149,7 +154,7 @@ func (c *Client) requestTimeshiftPlaylistURI(ctx context.Context, method, endpoi
 		return "", err
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return "", fmt.Errorf("failed to get playlist.m3u8 with %s: status=%d body=%q", method, resp.StatusCode, snippet(body))
+		return "", &PlaylistStatusError{Op: "get playlist.m3u8 with " + method, StatusCode: resp.StatusCode, Body: snippet(body)}
 	}
 
 	uri, err := m3u8.GetURI(bytes.NewReader(body))
@@ -159,6 +164,36 @@ func (c *Client) requestTimeshiftPlaylistURI(ctx context.Context, method, endpoi
 	return uri, nil
 }
 
+// newStreamRequest returns a request to the streaming endpoints
+// with the headers of the client's DeviceProfile.
+func (c *Client) newStreamRequest(ctx context.Context, method, endpoint string) (*http.Request, error) {
+	device, err := c.device()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("pragma", "no-cache")
+	req.Header.Set("Origin", defaultEndpoint)
+	req.Header.Set("Referer", defaultEndpoint+"/")
+	for k, v := range device.header() {
+		req.Header.Set(k, v)
+	}
+	if c.AreaID() != "" {
+		req.Header.Set("X-Radiko-AreaId", c.AreaID())
+	}
+	if c.AuthToken() != "" {
+		req.Header.Set(radikoAuthTokenHeader, c.AuthToken())
+	}
+	return req, nil
+}
+
 func snippet(body []byte) string {
 	s := strings.TrimSpace(string(body))
 	const max = 200
@@ -0,0 +1,73 @@
+package radiko
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultTokenTTL is shorter than the lifetime of radiko's auth_token (about 70 minutes).
+const DefaultTokenTTL = 60 * time.Minute
+
+// TokenManager keeps the auth_token of a Client enabled.
+// It is safe for concurrent use.
+type TokenManager struct {
+	// TTL is the interval of re-authorizing the client.
+	TTL time.Duration
+
+	client *Client
+
+	mu           sync.Mutex
+	authorizedAt time.Time
+}
+
+// NewTokenManager returns a new TokenManager for the client.
+func NewTokenManager(c *Client) *TokenManager {
+	return &TokenManager{
+		TTL:    DefaultTokenTTL,
+		client: c,
+	}
+}
+
+// Client returns the managed client.
+func (m *TokenManager) Client() *Client {
+	return m.client
+}
+
+// Authorize enables a new auth_token if the current one may be expired.
+func (m *TokenManager) Authorize(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.authorizedAt.IsZero() && time.Since(m.authorizedAt) < m.TTL {
+		return nil
+	}
+	return m.authorize(ctx)
+}
+
+// Refresh enables a new auth_token regardless of its age,
+// e.g. after radiko rejected the current one.
+func (m *TokenManager) Refresh(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.authorize(ctx)
+}
+
+// RefreshRejected enables a new auth_token after radiko rejected the token,
+// unless it has already been replaced, e.g. by another stream sharing the client.
+func (m *TokenManager) RefreshRejected(ctx context.Context, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.client.AuthToken() != token {
+		return nil
+	}
+	return m.authorize(ctx)
+}
+
+func (m *TokenManager) authorize(ctx context.Context) error {
+	if _, err := m.client.AuthorizeToken(ctx); err != nil {
+		return err
+	}
+	m.authorizedAt = time.Now()
+	return nil
+}
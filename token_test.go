@@ -0,0 +1,61 @@
+package radiko
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newAuthTestServer(t *testing.T, count *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/api/auth1":
+			*count++
+			w.Header().Set(radikoAuthTokenHeader, fmt.Sprintf("token%d", *count))
+			w.Header().Set(radikoKeyLentghHeader, "16")
+			w.Header().Set(radikoKeyOffsetHeader, "0")
+		case "/v2/api/auth2":
+			fmt.Fprint(w, "JP13,東京都,tokyo Japan")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestTokenManager(t *testing.T) {
+	var count int
+	ts := newAuthTestServer(t, &count)
+	defer ts.Close()
+
+	client := newTestClient(t, ts)
+	m := NewTokenManager(client)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := m.Authorize(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if count != 1 || client.AuthToken() != "token1" {
+		t.Errorf("Should authorize once: count=%d, token=%s", count, client.AuthToken())
+	}
+
+	if err := m.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if client.AuthToken() != "token2" {
+		t.Errorf("Should refresh the token: %s", client.AuthToken())
+	}
+
+	m.TTL = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	if err := m.Authorize(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if client.AuthToken() != "token3" {
+		t.Errorf("Should re-authorize an expired token: %s", client.AuthToken())
+	}
+}
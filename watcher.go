@@ -0,0 +1,236 @@
+package radiko
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	defaultWatcherRetryInterval = 10 * time.Second
+	defaultWatcherTTL           = 60 * time.Second
+	watcherEventBuffer          = 16
+)
+
+// ProgramChanged is delivered by Watcher when the program on the air changes.
+type ProgramChanged struct {
+	// Station has the station's meta-info. Its programs are not set.
+	Station Station
+	// Previous is the program which was on the air, nil at the first observation.
+	Previous *Prog
+	// Current is the program on the air, nil if the station is off the air
+	// until a later program in the response.
+	Current *Prog
+}
+
+// Watcher tracks the programs on the air with GetNowPrograms.
+//
+// The next fetch is scheduled when the earliest current program ends
+// (or the next program starts), judged by the server-corrected time (see Client.Now),
+// and at the latest after <ttl> of the response.
+// A station whose programs in the response have all ended is regarded as outdated,
+// e.g. the schedule is not updated yet at the boundary, and is reloaded after RetryInterval
+// without a change.
+type Watcher struct {
+	// StationIDs are the stations to watch. Empty means all stations in the area.
+	StationIDs []string
+	// OnChange is called for each change if set.
+	// Otherwise, changes are delivered to Events.
+	OnChange func(ProgramChanged)
+	// OnError is called for each failed request if set.
+	// Otherwise, errors are delivered to Errors.
+	OnError func(error)
+	// RetryInterval is the interval of reloading the programs when the response
+	// is outdated or the request fails.
+	RetryInterval time.Duration
+
+	client  *Client
+	events  chan ProgramChanged
+	errors  chan error
+	now     func() time.Time
+	current map[string]*Prog
+}
+
+// NewWatcher returns a new Watcher of the stations.
+func NewWatcher(c *Client, stationIDs ...string) *Watcher {
+	return &Watcher{
+		StationIDs:    stationIDs,
+		RetryInterval: defaultWatcherRetryInterval,
+		client:        c,
+		events:        make(chan ProgramChanged, watcherEventBuffer),
+		errors:        make(chan error, watcherEventBuffer),
+		now:           c.Now,
+		current:       map[string]*Prog{},
+	}
+}
+
+// Events returns the channel of changes, which is closed when Run returns.
+func (w *Watcher) Events() <-chan ProgramChanged {
+	return w.events
+}
+
+// Errors returns the channel of the errors of the requests, which is closed when Run returns.
+// The programs are reloaded after RetryInterval on an error.
+// Errors are dropped while the channel is full, so that it need not be received.
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Run watches the programs until ctx is done.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.events)
+	defer close(w.errors)
+
+	for {
+		wait := w.RetryInterval
+		d, err := w.client.getNowProgramsData(ctx)
+		if err != nil && ctx.Err() == nil {
+			w.deliverError(err)
+		}
+		if err == nil {
+			var changes []ProgramChanged
+			changes, wait = w.update(d)
+			for _, e := range changes {
+				if err := w.deliver(ctx, e); err != nil {
+					return err
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (w *Watcher) deliver(ctx context.Context, e ProgramChanged) error {
+	if w.OnChange != nil {
+		w.OnChange(e)
+		return nil
+	}
+	select {
+	case w.events <- e:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Watcher) deliverError(err error) {
+	if w.OnError != nil {
+		w.OnError(err)
+		return
+	}
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
+
+// update records the current programs in the response,
+// and returns the changes and the duration until the next fetch.
+func (w *Watcher) update(d *stationsData) ([]ProgramChanged, time.Duration) {
+	serverNow := w.now()
+	ttl := defaultWatcherTTL
+	if d.TTL > 0 {
+		ttl = time.Duration(d.TTL) * time.Second
+	}
+
+	var changes []ProgramChanged
+	var next time.Time
+	outdated := false
+	for _, s := range d.stations() {
+		if !w.watching(s.ID) {
+			continue
+		}
+
+		cur := currentProg(s, serverNow)
+		t := nextBoundary(s, serverNow)
+		if cur == nil && t.IsZero() {
+			// The programs after the boundary are not in the response yet.
+			outdated = true
+			continue
+		}
+		prev, seen := w.current[s.ID]
+		if !seen || !sameProg(prev, cur) {
+			w.current[s.ID] = cur
+			station := s
+			station.Scd = Scd{}
+			station.Progs = Progs{}
+			changes = append(changes, ProgramChanged{Station: station, Previous: prev, Current: cur})
+		}
+
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	wait := ttl
+	if !next.IsZero() {
+		if d := next.Sub(serverNow); d <= 0 {
+			wait = w.RetryInterval
+		} else if d < ttl {
+			wait = d
+		}
+	}
+	if outdated && wait > w.RetryInterval {
+		wait = w.RetryInterval
+	}
+	return changes, wait
+}
+
+func (w *Watcher) watching(stationID string) bool {
+	if len(w.StationIDs) == 0 {
+		return true
+	}
+	for _, id := range w.StationIDs {
+		if id == stationID {
+			return true
+		}
+	}
+	return false
+}
+
+// currentProg returns the program of the station on the air at t.
+func currentProg(s Station, t time.Time) *Prog {
+	for _, p := range s.Programs() {
+		ft, err := p.StartTime()
+		if err != nil {
+			continue
+		}
+		to, err := p.EndTime()
+		if err != nil {
+			continue
+		}
+		if !t.Before(ft) && t.Before(to) {
+			p := p
+			return &p
+		}
+	}
+	return nil
+}
+
+// nextBoundary returns the earliest start or end time of the station's programs after t.
+func nextBoundary(s Station, t time.Time) time.Time {
+	var next time.Time
+	for _, p := range s.Programs() {
+		for _, f := range []func() (time.Time, error){p.StartTime, p.EndTime} {
+			b, err := f()
+			if err != nil || !b.After(t) {
+				continue
+			}
+			if next.IsZero() || b.Before(next) {
+				next = b
+			}
+		}
+	}
+	return next
+}
+
+func sameProg(a, b *Prog) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Ft == b.Ft && a.Title == b.Title
+}
@@ -0,0 +1,133 @@
+package radiko
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWatcher_update(t *testing.T) {
+	d := loadTestStationsData(t)
+	w := NewWatcher(nil, "LFR")
+	w.now = func() time.Time { return time.Unix(d.Srvtime, 0) }
+
+	// srvtime is 2016-11-12 23:23:47 JST, and the program ends at 23:30:00.
+	changes, wait := w.update(d)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, but %d", len(changes))
+	}
+	e := changes[0]
+	if e.Station.ID != "LFR" || e.Previous != nil || e.Current == nil || e.Current.Ft != "20161112230000" {
+		t.Errorf("unexpected change: %+v", e)
+	}
+	if len(e.Station.Programs()) != 0 {
+		t.Error("Programs of the station should not be set.")
+	}
+	// The ttl of 300s is earlier than the end of the program.
+	if expected := 300 * time.Second; wait != expected {
+		t.Errorf("expected %s, but %s", expected, wait)
+	}
+
+	changes, _ = w.update(d)
+	if len(changes) != 0 {
+		t.Errorf("expected no change, but %+v", changes)
+	}
+
+	d.Srvtime += int64(6*time.Minute/time.Second + 13)
+	d.TTL = 7200
+	changes, wait = w.update(d)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, but %d", len(changes))
+	}
+	e = changes[0]
+	if e.Previous == nil || e.Previous.Ft != "20161112230000" || e.Current == nil || e.Current.Ft != "20161112233000" {
+		t.Errorf("unexpected change: %+v", e)
+	}
+	if expected := 90 * time.Minute; wait != expected {
+		t.Errorf("expected %s, but %s", expected, wait)
+	}
+
+	// The response is outdated after the last program.
+	d.Srvtime += int64(wait / time.Second)
+	changes, wait = w.update(d)
+	if len(changes) != 0 {
+		t.Errorf("Should not regard the station as off the air: %+v", changes)
+	}
+	if wait != w.RetryInterval {
+		t.Errorf("expected %s, but %s", w.RetryInterval, wait)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	boundary := time.Now().Truncate(time.Second).Add(time.Second).In(loc)
+	const layout = "20060102150405"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<radiko><ttl>300</ttl><srvtime>%d</srvtime><stations><station id="LFR"><name>ニッポン放送</name><progs>`+
+			`<prog ft="%s" to="%s"><title>first</title></prog>`+
+			`<prog ft="%s" to="%s"><title>second</title></prog>`+
+			`</progs></station></stations></radiko>`,
+			time.Now().Unix(),
+			boundary.Add(-time.Hour).Format(layout), boundary.Format(layout),
+			boundary.Format(layout), boundary.Add(time.Hour).Format(layout))
+	}))
+	defer ts.Close()
+
+	w := NewWatcher(newTestClient(t, ts))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go w.Run(ctx)
+
+	for _, expected := range []string{"first", "second"} {
+		e, ok := <-w.Events()
+		if !ok {
+			t.Fatal("Events should not be closed.")
+		}
+		if e.Current == nil || e.Current.Title != expected {
+			t.Errorf("expected %s, but %+v", expected, e.Current)
+		}
+	}
+	cancel()
+	for range w.Events() {
+	}
+}
+
+func TestWatcher_Run_Error(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	now := time.Now().In(loc)
+	const layout = "20060102150405"
+
+	failed := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !failed {
+			failed = true
+			http.Error(w, "service unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `<radiko><ttl>300</ttl><srvtime>%d</srvtime><stations><station id="LFR"><name>ニッポン放送</name><progs>`+
+			`<prog ft="%s" to="%s"><title>first</title></prog>`+
+			`</progs></station></stations></radiko>`,
+			now.Unix(), now.Add(-time.Hour).Format(layout), now.Add(time.Hour).Format(layout))
+	}))
+	defer ts.Close()
+
+	w := NewWatcher(newTestClient(t, ts))
+	w.RetryInterval = 10 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	go w.Run(ctx)
+
+	if err, ok := <-w.Errors(); !ok || err == nil {
+		t.Error("Should detect an error.")
+	}
+	if e, ok := <-w.Events(); !ok || e.Current == nil || e.Current.Title != "first" {
+		t.Errorf("Should retry after the error: %+v", e)
+	}
+	cancel()
+	for range w.Events() {
+	}
+}